@@ -0,0 +1,135 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+MaxParameters - maximum number of bind parameters of a Postgres statement
+*/
+const MaxParameters = 65535
+
+/*
+RowShapeError - returned by InsertBatch when row is not []interface{} or has wrong number of values
+*/
+type RowShapeError struct {
+	Row  int
+	Type string
+	Len  int
+	Want int
+}
+
+func (e *RowShapeError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("pg: batch row %d is %s, expected []interface{}", e.Row, e.Type)
+	}
+	return fmt.Sprintf("pg: batch row %d has %d values, expected %d", e.Row, e.Len, e.Want)
+}
+
+/*
+BatchProgress - passed to Mapper.BatchProgress after every inserted chunk. Batch fitting one statement
+and batch loaded with COPY are reported as a single chunk
+*/
+type BatchProgress struct {
+	Chunk        int // number of inserted chunk, starting from 1
+	Chunks       int
+	Rows         int   // rows inserted so far
+	RowsAffected int64 // rows affected so far
+}
+
+/*
+InsertBatchAffected - same as InsertBatch but returns number of affected rows. Rows are split into
+chunks fitting MaxParameters which are inserted in one transaction
+*/
+func (pgm *Mapper) InsertBatchAffected(fields []string, rows []interface{}, onDuplicate interface{}) (int64, error) {
+	return pgm.InsertBatchAffectedContext(context.Background(), fields, rows, onDuplicate)
+}
+
+/*
+InsertBatchAffectedContext - InsertBatchAffected with context
+*/
+func (pgm *Mapper) InsertBatchAffectedContext(ctx context.Context, fields []string, rows []interface{}, onDuplicate interface{}) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if err := validateRows(fields, rows); err != nil {
+		return 0, err
+	}
+	if pgm.CopyThreshold > 0 && len(rows) > pgm.CopyThreshold {
+		affected, err := pgm.copyBatch(ctx, fields, rows, onDuplicate)
+		if err != nil {
+			return 0, err
+		}
+		pgm.reportProgress(BatchProgress{Chunk: 1, Chunks: 1, Rows: len(rows), RowsAffected: affected})
+		return affected, nil
+	}
+	size := MaxParameters / len(fields)
+	if len(rows) <= size {
+		affected, err := pgm.insertChunk(ctx, fields, rows, onDuplicate)
+		if err != nil {
+			return 0, err
+		}
+		pgm.reportProgress(BatchProgress{Chunk: 1, Chunks: 1, Rows: len(rows), RowsAffected: affected})
+		return affected, nil
+	}
+
+	chunks := (len(rows) + size - 1) / size
+	var progress BatchProgress
+	err := pgm.Tx(ctx, func(tx *Mapper) error {
+		for start := 0; start < len(rows); start += size {
+			end := start + size
+			if end > len(rows) {
+				end = len(rows)
+			}
+			affected, err := tx.insertChunk(ctx, fields, rows[start:end], onDuplicate)
+			if err != nil {
+				return err
+			}
+			progress.Chunk++
+			progress.Chunks = chunks
+			progress.Rows = end
+			progress.RowsAffected += affected
+			pgm.reportProgress(progress)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return progress.RowsAffected, nil
+}
+
+func (pgm *Mapper) reportProgress(progress BatchProgress) {
+	if pgm.BatchProgress != nil {
+		pgm.BatchProgress(progress)
+	}
+}
+
+func (pgm *Mapper) insertChunk(ctx context.Context, fields []string, rows []interface{}, onDuplicate interface{}) (int64, error) {
+	SQL, values := pgm.generateBatchQuery(fields, rows, onDuplicate)
+	result, err := pgm.executeResult(ctx, SQL, values)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/*
+validateRows - checks that every row is []interface{} with a value for every field
+*/
+func validateRows(fields []string, rows []interface{}) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("pg: batch fields are empty")
+	}
+	for i, row := range rows {
+		r, ok := row.([]interface{})
+		if !ok {
+			return &RowShapeError{Row: i, Type: fmt.Sprintf("%T", row)}
+		}
+		if len(r) != len(fields) {
+			return &RowShapeError{Row: i, Len: len(r), Want: len(fields)}
+		}
+	}
+	return nil
+}
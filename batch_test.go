@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRows(t *testing.T) {
+	fields := []string{"id", "name"}
+	tests := []struct {
+		name string
+		rows []interface{}
+		want *RowShapeError
+	}{
+		{"valid", []interface{}{[]interface{}{1, "a"}, []interface{}{2, "b"}}, nil},
+		{"wrong type", []interface{}{[]interface{}{1, "a"}, []string{"2", "b"}}, &RowShapeError{Row: 1, Type: "[]string"}},
+		{"wrong length", []interface{}{[]interface{}{1}}, &RowShapeError{Row: 0, Len: 1, Want: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRows(fields, tt.rows)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			var shapeErr *RowShapeError
+			if !errors.As(err, &shapeErr) || *shapeErr != *tt.want {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if err := validateRows(nil, []interface{}{[]interface{}{}}); err == nil {
+		t.Error("empty fields are accepted")
+	}
+}
+
+func TestGenerateBatchQuery(t *testing.T) {
+	pgm := &Mapper{Source: "users"}
+	rows := []interface{}{[]interface{}{1, "a"}, []interface{}{2, "b"}}
+	SQL, values := pgm.generateBatchQuery([]string{"id", "name"}, rows, "(id) DO NOTHING")
+	want := "insert into users (id,name) values ($1,$2),($3,$4) ON CONFLICT (id) DO NOTHING"
+	if SQL != want {
+		t.Errorf("SQL = %q, want %q", SQL, want)
+	}
+	if !reflect.DeepEqual(values, []interface{}{1, "a", 2, "b"}) {
+		t.Errorf("values = %v", values)
+	}
+}
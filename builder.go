@@ -0,0 +1,309 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+/*
+keywords - reserved words which look like identifiers but are values or expression prefixes, never quoted
+*/
+var keywords = map[string]bool{
+	"all": true, "any": true, "array": true, "case": true, "cast": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true, "distinct": true, "exists": true,
+	"false": true, "localtime": true, "localtimestamp": true, "not": true, "null": true,
+	"session_user": true, "some": true, "true": true, "user": true,
+}
+
+/*
+SelectBuilder - composes SELECT query. Conditions use $n placeholders numbered from 1 inside every
+call, builder renumbers them in the final query
+*/
+type SelectBuilder struct {
+	mapper  *Mapper
+	fields  []string
+	from    string
+	joins   []string
+	where   []string
+	groupBy []string
+	having  []string
+	orderBy []string
+	limit   int
+	offset  int
+	args    []interface{}
+	err     error
+}
+
+/*
+Select - starts SELECT query from mapper Source. Without fields all columns are selected
+*/
+func (pgm *Mapper) Select(fields ...string) *SelectBuilder {
+	return &SelectBuilder{mapper: pgm, fields: fields, from: pgm.Source, limit: -1, offset: -1}
+}
+
+/*
+From - sets source table, "table alias" form is supported
+*/
+func (b *SelectBuilder) From(source string) *SelectBuilder {
+	b.from = source
+	return b
+}
+
+/*
+Where - adds condition (string with placeholders or Where), conditions are joined with AND
+*/
+func (b *SelectBuilder) Where(query interface{}, args ...interface{}) *SelectBuilder {
+	condition, args, err := buildCondition(query, args)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if condition != "" {
+		b.where = append(b.where, "("+b.bind(condition, args)+")")
+	}
+	return b
+}
+
+/*
+Join - adds INNER JOIN
+*/
+func (b *SelectBuilder) Join(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("JOIN", table, on, args)
+}
+
+/*
+LeftJoin - adds LEFT JOIN
+*/
+func (b *SelectBuilder) LeftJoin(table, on string, args ...interface{}) *SelectBuilder {
+	return b.join("LEFT JOIN", table, on, args)
+}
+
+func (b *SelectBuilder) join(kind, table, on string, args []interface{}) *SelectBuilder {
+	b.joins = append(b.joins, kind+" "+quoteExpression(table)+" ON "+b.bind(on, args))
+	return b
+}
+
+/*
+GroupBy - adds GROUP BY columns
+*/
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+/*
+Having - adds HAVING condition, conditions are joined with AND
+*/
+func (b *SelectBuilder) Having(condition string, args ...interface{}) *SelectBuilder {
+	b.having = append(b.having, "("+b.bind(condition, args)+")")
+	return b
+}
+
+/*
+OrderBy - adds ORDER BY columns, e.g. "created_at DESC"
+*/
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+/*
+Limit - sets LIMIT
+*/
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+/*
+Offset - sets OFFSET
+*/
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+/*
+bind - renumbers placeholders of the clause after already bound arguments
+*/
+func (b *SelectBuilder) bind(clause string, args []interface{}) string {
+	clause = rebind(clause, len(b.args))
+	b.args = append(b.args, args...)
+	return clause
+}
+
+/*
+ToSQL - returns query and its arguments
+*/
+func (b *SelectBuilder) ToSQL() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	fields := "*"
+	if len(b.fields) > 0 {
+		fields = quoteExpressions(b.fields)
+	}
+	SQL := "SELECT " + fields + " FROM " + quoteExpression(b.from)
+	if len(b.joins) > 0 {
+		SQL += " " + strings.Join(b.joins, " ")
+	}
+	if len(b.where) > 0 {
+		SQL += " WHERE " + strings.Join(b.where, " AND ")
+	}
+	if len(b.groupBy) > 0 {
+		SQL += " GROUP BY " + quoteExpressions(b.groupBy)
+	}
+	if len(b.having) > 0 {
+		SQL += " HAVING " + strings.Join(b.having, " AND ")
+	}
+	if len(b.orderBy) > 0 {
+		SQL += " ORDER BY " + quoteExpressions(b.orderBy)
+	}
+	if b.limit >= 0 {
+		SQL += " LIMIT " + strconv.Itoa(b.limit)
+	}
+	if b.offset >= 0 {
+		SQL += " OFFSET " + strconv.Itoa(b.offset)
+	}
+	return SQL, b.args, nil
+}
+
+/*
+Query - executes query
+*/
+func (b *SelectBuilder) Query() (*sql.Rows, error) {
+	return b.QueryContext(context.Background())
+}
+
+/*
+QueryContext - Query with context
+*/
+func (b *SelectBuilder) QueryContext(ctx context.Context) (*sql.Rows, error) {
+	SQL, args, err := b.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	return b.mapper.query(ctx, b.from, SQL, args)
+}
+
+/*
+rebind - shifts $n placeholders by offset. Quoted literals, identifiers and dollar-quoted strings are left intact
+*/
+func rebind(clause string, offset int) string {
+	if offset == 0 {
+		return clause
+	}
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(clause); i++ {
+		c := clause[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && (i == 0 || !isIdentifierChar(clause[i-1])) && dollarTag(clause[i:]) != "":
+			tag := dollarTag(clause[i:])
+			end := strings.Index(clause[i+len(tag):], tag)
+			if end < 0 {
+				out.WriteString(clause[i:])
+				return out.String()
+			}
+			end += i + 2*len(tag)
+			out.WriteString(clause[i:end])
+			i = end - 1
+			continue
+		case c == '$' && i+1 < len(clause) && isDigit(clause[i+1]):
+			j := i + 1
+			for j < len(clause) && isDigit(clause[j]) {
+				j++
+			}
+			n, _ := strconv.Atoi(clause[i+1 : j])
+			out.WriteString("$" + strconv.Itoa(n+offset))
+			i = j - 1
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+/*
+dollarTag - returns opening "$tag$" (or "$$") delimiter of dollar-quoted string at the start of s
+*/
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case isIdentifierChar(c) && (i > 1 || !isDigit(c)):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func quoteExpressions(expressions []string) string {
+	quoted := make([]string, len(expressions))
+	for i, expression := range expressions {
+		quoted[i] = quoteExpression(expression)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+/*
+quoteExpression - quotes identifiers in "name", "table.*", "name AS alias", "table alias" and
+"column DESC" forms with quoteIdentifier. Other expressions (function calls, keywords etc.) are returned as is
+*/
+func quoteExpression(expression string) string {
+	expression = strings.TrimSpace(expression)
+	if expression == "*" {
+		return expression
+	}
+	if strings.HasSuffix(expression, ".*") && isIdentifier(strings.TrimSuffix(expression, ".*")) {
+		return quoteIdentifier(strings.TrimSuffix(expression, ".*")) + ".*"
+	}
+	if isIdentifier(expression) {
+		return quoteIdentifier(expression)
+	}
+	parts := strings.Fields(expression)
+	if len(parts) < 2 || !isIdentifier(parts[0]) {
+		return expression
+	}
+	rest := parts[1:]
+	switch strings.ToUpper(rest[0]) {
+	case "ASC", "DESC", "NULLS":
+		return quoteIdentifier(parts[0]) + " " + strings.Join(rest, " ")
+	case "AS":
+		if len(rest) == 2 && isIdentifier(rest[1]) {
+			return quoteIdentifier(parts[0]) + " AS " + quoteIdentifier(rest[1])
+		}
+	default:
+		if len(rest) == 1 && isIdentifier(rest[0]) {
+			return quoteIdentifier(parts[0]) + " " + quoteIdentifier(rest[0])
+		}
+	}
+	return expression
+}
+
+func isIdentifier(name string) bool {
+	return identifierRe.MatchString(name) && !keywords[strings.ToLower(name)]
+}
@@ -0,0 +1,83 @@
+package pg
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name   string
+		clause string
+		offset int
+		want   string
+	}{
+		{"no offset", "a = $1", 0, "a = $1"},
+		{"placeholders", "a = $1 AND b = $2", 3, "a = $4 AND b = $5"},
+		{"multi digit", "a = $10", 2, "a = $12"},
+		{"string literal", "a = '$1' AND b = $1", 3, "a = '$1' AND b = $4"},
+		{"escaped quote", "a = 'it''s $1' AND b = $1", 3, "a = 'it''s $1' AND b = $4"},
+		{"quoted identifier", `"$1" = $1`, 3, `"$1" = $4`},
+		{"dollar quoted", "a = $$x$1$$ AND b = $1", 3, "a = $$x$1$$ AND b = $4"},
+		{"tagged dollar quoted", "a = $fn$ $1 $$ $fn$ AND b = $1", 3, "a = $fn$ $1 $$ $fn$ AND b = $4"},
+		{"unterminated dollar quoted", "a = $$ $1", 3, "a = $$ $1"},
+		{"dollar in identifier", "a$b$ = $1", 3, "a$b$ = $4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rebind(tt.clause, tt.offset); got != tt.want {
+				t.Errorf("rebind(%q, %d) = %q, want %q", tt.clause, tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"*", "*"},
+		{"id", `"id"`},
+		{"userId", `"userid"`},
+		{"u.*", `"u".*`},
+		{"public.users", `"public"."users"`},
+		{"order", `"order"`},
+		{"id AS key", `"id" AS "key"`},
+		{"users u", `"users" "u"`},
+		{"created_at DESC", `"created_at" DESC`},
+		{"name ASC NULLS LAST", `"name" ASC NULLS LAST`},
+		{"count(*)", "count(*)"},
+		{"true", "true"},
+		{"NULL", "NULL"},
+		{"now() AS ts", "now() AS ts"},
+		{"DISTINCT id", "DISTINCT id"},
+		{"current_timestamp", "current_timestamp"},
+		{`"userId"`, `"userId"`},
+	}
+	for _, tt := range tests {
+		if got := quoteExpression(tt.expression); got != tt.want {
+			t.Errorf("quoteExpression(%q) = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestSelectBuilderToSQL(t *testing.T) {
+	pgm := &Mapper{Source: "users"}
+	SQL, args, err := pgm.Select("id", "name").
+		Where("status = $1", "active").
+		Where(Where{"role": []string{"admin", "owner"}}).
+		LeftJoin("orders o", "o.user_id = users.id AND o.total > $1", 100).
+		OrderBy("id DESC").
+		Limit(10).
+		Offset(20).
+		ToSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT "id", "name" FROM "users" LEFT JOIN "orders" "o" ON o.user_id = users.id AND o.total > $3` +
+		` WHERE (status = $1) AND ("role" = ANY($2)) ORDER BY "id" DESC LIMIT 10 OFFSET 20`
+	if SQL != want {
+		t.Errorf("SQL = %q\nwant  %q", SQL, want)
+	}
+	if len(args) != 3 || args[0] != "active" || args[2] != 100 {
+		t.Errorf("args = %v", args)
+	}
+}
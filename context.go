@@ -0,0 +1,13 @@
+package pg
+
+import "context"
+
+/*
+withTimeout - applies QueryTimeout to ctx
+*/
+func (pgm *Mapper) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pgm.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, pgm.QueryTimeout)
+}
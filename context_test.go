@@ -0,0 +1,51 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type contextHook struct {
+	contexts *[]context.Context
+}
+
+func (h contextHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	*h.contexts = append(*h.contexts, ctx)
+	return ctx
+}
+
+func (h contextHook) AfterQuery(ctx context.Context, event *QueryEvent) {}
+
+func TestQueryTimeoutRelease(t *testing.T) {
+	pgm, _ := fakeMapper(t, "users")
+	pgm.QueryTimeout = time.Hour
+	var contexts []context.Context
+	pgm.AddQueryHook(contextHook{&contexts})
+
+	var dest []struct {
+		ID int64 `db:"id"`
+	}
+	if err := pgm.LoadInto(&dest, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := pgm.Exec("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if len(contexts) != 2 {
+		t.Fatalf("got %d queries, want 2", len(contexts))
+	}
+	if _, ok := contexts[0].Deadline(); !ok {
+		t.Error("LoadInto query has no QueryTimeout deadline")
+	}
+	if err := contexts[0].Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadInto context error = %v after return, want context.Canceled", err)
+	}
+	if _, ok := contexts[1].Deadline(); ok {
+		t.Error("Exec query has QueryTimeout deadline, rows are read by the caller")
+	}
+}
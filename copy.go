@@ -0,0 +1,169 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lib/pq"
+)
+
+/*
+RowSource - rows streamed by CopyFrom. Next returns io.EOF when there are no more rows
+*/
+type RowSource interface {
+	Next() ([]interface{}, error)
+}
+
+/*
+RowSourceFunc - function implementing RowSource
+*/
+type RowSourceFunc func() ([]interface{}, error)
+
+/*
+Next - returns next row
+*/
+func (f RowSourceFunc) Next() ([]interface{}, error) {
+	return f()
+}
+
+/*
+RowsFromSlice - RowSource over rows in InsertBatch format: every row is []interface{}, other rows
+are reported with *RowShapeError
+*/
+func RowsFromSlice(rows []interface{}) RowSource {
+	i := 0
+	return RowSourceFunc(func() ([]interface{}, error) {
+		if i >= len(rows) {
+			return nil, io.EOF
+		}
+		row, ok := rows[i].([]interface{})
+		if !ok {
+			return nil, &RowShapeError{Row: i, Type: fmt.Sprintf("%T", rows[i])}
+		}
+		i++
+		return row, nil
+	})
+}
+
+/*
+RowsFromChannel - RowSource reading rows from ch until it is closed
+*/
+func RowsFromChannel(ch <-chan []interface{}) RowSource {
+	return RowSourceFunc(func() ([]interface{}, error) {
+		row, ok := <-ch
+		if !ok {
+			return nil, io.EOF
+		}
+		return row, nil
+	})
+}
+
+var copyTables uint64
+
+/*
+CopyFrom - loads rows into mapper Source with COPY and returns number of copied rows. Runs in its own
+transaction or in the transaction of tx mapper. QueryTimeout is not applied. Fields and Source are
+written as in SQL like in InsertBatch: unquoted names are folded to lower case, quoted are kept as is
+*/
+func (pgm *Mapper) CopyFrom(fields []string, source RowSource) (int64, error) {
+	return pgm.CopyFromContext(context.Background(), fields, source)
+}
+
+/*
+CopyFromContext - CopyFrom with context
+*/
+func (pgm *Mapper) CopyFromContext(ctx context.Context, fields []string, source RowSource) (int64, error) {
+	var count int64
+	err := pgm.Tx(ctx, func(tx *Mapper) (err error) {
+		count, err = tx.copyIn(ctx, pgm.Source, fields, source)
+		return err
+	})
+	return count, err
+}
+
+/*
+copyBatch - InsertBatch through COPY. Without onDuplicate rows are copied to Source directly,
+otherwise they are copied to temporary table and inserted with INSERT ... SELECT ... ON CONFLICT
+*/
+func (pgm *Mapper) copyBatch(ctx context.Context, fields []string, rows []interface{}, onDuplicate interface{}) (int64, error) {
+	if onDuplicate == nil {
+		return pgm.CopyFromContext(ctx, fields, RowsFromSlice(rows))
+	}
+	var count int64
+	err := pgm.Tx(ctx, func(tx *Mapper) error {
+		temp := "insert_batch_" + strconv.FormatUint(atomic.AddUint64(&copyTables, 1), 10)
+		columns := strings.Join(fields, ",")
+		SQL := "CREATE TEMP TABLE " + temp + " ON COMMIT DROP AS SELECT " + columns + " FROM " + pgm.Source + " WITH NO DATA"
+		if _, err := tx.execDirect(ctx, temp, SQL); err != nil {
+			return err
+		}
+		if _, err := tx.copyIn(ctx, temp, fields, RowsFromSlice(rows)); err != nil {
+			return err
+		}
+		SQL = "INSERT INTO " + pgm.Source + " (" + columns + ") SELECT " + columns + " FROM " + temp +
+			" ON CONFLICT " + onDuplicate.(string)
+		result, err := tx.execDirect(ctx, pgm.Source, SQL)
+		if err != nil {
+			return err
+		}
+		count, err = result.RowsAffected()
+		return err
+	})
+	return count, err
+}
+
+/*
+copyIn - streams rows into table with COPY. Mapper must be bound to transaction
+*/
+func (pgm *Mapper) copyIn(ctx context.Context, table string, fields []string, source RowSource) (int64, error) {
+	SQL := copyInStatement(table, fields)
+	ctx, event := pgm.beginQuery(ctx, table, SQL, nil)
+	stmt, err := pgm.tx.PrepareContext(ctx, SQL)
+	if err != nil {
+		return 0, pgm.endQuery(ctx, event, -1, err)
+	}
+	defer stmt.Close()
+	count, err := copyRows(ctx, stmt, source)
+	return count, pgm.endQuery(ctx, event, count, err)
+}
+
+func copyRows(ctx context.Context, stmt *sql.Stmt, source RowSource) (int64, error) {
+	var count int64
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+/*
+copyInStatement - COPY statement for table and fields written as in SQL. pq quotes every name, so they
+are converted to the names PostgreSQL resolves them to first
+*/
+func copyInStatement(table string, fields []string) string {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = resolveName(field)
+	}
+	if parts := splitIdentifier(table); len(parts) == 2 {
+		return pq.CopyInSchema(resolveName(parts[0]), resolveName(parts[1]), columns...)
+	}
+	return pq.CopyIn(resolveName(table), columns...)
+}
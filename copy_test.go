@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCopyInStatement(t *testing.T) {
+	tests := []struct {
+		table  string
+		fields []string
+		want   string
+	}{
+		{"users", []string{"id", "name"}, `COPY "users" ("id", "name") FROM STDIN`},
+		{"Users", []string{"userId"}, `COPY "users" ("userid") FROM STDIN`},
+		{`"Users"`, []string{`"userId"`}, `COPY "Users" ("userId") FROM STDIN`},
+		{"public.users", []string{"id"}, `COPY "public"."users" ("id") FROM STDIN`},
+		{`"Public"."Users"`, []string{`"a""b"`}, `COPY "Public"."Users" ("a""b") FROM STDIN`},
+	}
+	for _, tt := range tests {
+		if got := copyInStatement(tt.table, tt.fields); got != tt.want {
+			t.Errorf("copyInStatement(%q, %q) = %q, want %q", tt.table, tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestRowsFromSlice(t *testing.T) {
+	source := RowsFromSlice([]interface{}{[]interface{}{1, "a"}, "bad"})
+	row, err := source.Next()
+	if err != nil || len(row) != 2 {
+		t.Fatalf("Next() = %v, %v", row, err)
+	}
+	_, err = source.Next()
+	var shapeErr *RowShapeError
+	if !errors.As(err, &shapeErr) || shapeErr.Row != 1 || shapeErr.Type != "string" {
+		t.Fatalf("Next() error = %v, want *RowShapeError for row 1", err)
+	}
+
+	source = RowsFromSlice(nil)
+	if _, err := source.Next(); err != io.EOF {
+		t.Fatalf("Next() on empty slice = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound - no rows matched the query, also matches sql.ErrNoRows
+	ErrNotFound = errors.New("pg: not found")
+	// ErrUniqueViolation - unique or primary key constraint violated (23505)
+	ErrUniqueViolation = errors.New("pg: unique violation")
+	// ErrForeignKeyViolation - foreign key constraint violated (23503)
+	ErrForeignKeyViolation = errors.New("pg: foreign key violation")
+	// ErrCheckViolation - check constraint violated (23514)
+	ErrCheckViolation = errors.New("pg: check violation")
+	// ErrConnection - connection to PostgreSQL failed or was lost
+	ErrConnection = errors.New("pg: connection error")
+)
+
+/*
+Error - database error returned by Mapper. Kind is one of Err* sentinels (nil if error is not classified),
+fields are copied from *pq.Error which is still available with errors.As
+*/
+type Error struct {
+	Kind       error
+	Code       string // SQLSTATE
+	Constraint string
+	Schema     string
+	Table      string
+	Column     string
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+/*
+Is - makes errors.Is(err, ErrUniqueViolation) and similar checks match Kind
+*/
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+/*
+SQLState - returns SQLSTATE code of err or empty string if err is not a PostgreSQL error
+*/
+func SQLState(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+	return string(pqErr.Code)
+}
+
+/*
+Constraint - returns name of the constraint violated by err or empty string
+*/
+func Constraint(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+	return pqErr.Constraint
+}
+
+/*
+wrapError - classifies driver error, errors already wrapped and context errors are returned as is
+*/
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var wrapped *Error
+	if errors.As(err, &wrapped) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err == sql.ErrNoRows {
+		return &Error{Kind: ErrNotFound, Message: err.Error(), Err: err}
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &Error{
+			Kind:       errorKind(pqErr.Code),
+			Code:       string(pqErr.Code),
+			Constraint: pqErr.Constraint,
+			Schema:     pqErr.Schema,
+			Table:      pqErr.Table,
+			Column:     pqErr.Column,
+			Message:    pqErr.Message,
+			Err:        err,
+		}
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return &Error{Kind: ErrConnection, Message: err.Error(), Err: err}
+	}
+	return err
+}
+
+func errorKind(code pq.ErrorCode) error {
+	switch {
+	case code == "23505":
+		return ErrUniqueViolation
+	case code == "23503":
+		return ErrForeignKeyViolation
+	case code == "23514":
+		return ErrCheckViolation
+	case code.Class() == "08", code == "57P01", code == "57P02", code == "57P03":
+		return ErrConnection
+	}
+	return nil
+}
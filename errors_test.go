@@ -0,0 +1,95 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		code pq.ErrorCode
+		want error
+	}{
+		{"23505", ErrUniqueViolation},
+		{"23503", ErrForeignKeyViolation},
+		{"23514", ErrCheckViolation},
+		{"08006", ErrConnection},
+		{"08001", ErrConnection},
+		{"57P01", ErrConnection},
+		{"23502", nil},
+		{"40001", nil},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.code); got != tt.want {
+			t.Errorf("errorKind(%s) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	pqErr := &pq.Error{Code: "23505", Constraint: "users_email_key", Table: "users", Column: "email", Message: "duplicate key"}
+	err := wrapError(fmt.Errorf("insert: %w", pqErr))
+
+	var pgErr *Error
+	if !errors.As(err, &pgErr) {
+		t.Fatalf("wrapError() = %T, want *Error", err)
+	}
+	if pgErr.Code != "23505" || pgErr.Constraint != "users_email_key" || pgErr.Table != "users" || pgErr.Column != "email" {
+		t.Errorf("error = %+v", pgErr)
+	}
+	if !errors.Is(err, ErrUniqueViolation) || errors.Is(err, ErrCheckViolation) {
+		t.Error("errors.Is does not match the kind")
+	}
+	var unwrapped *pq.Error
+	if !errors.As(err, &unwrapped) || unwrapped != pqErr {
+		t.Error("*pq.Error is not reachable with errors.As")
+	}
+	if SQLState(err) != "23505" || Constraint(err) != "users_email_key" {
+		t.Errorf("SQLState = %q, Constraint = %q", SQLState(err), Constraint(err))
+	}
+	if wrapError(err) != err {
+		t.Error("wrapped error is wrapped again")
+	}
+}
+
+func TestWrapErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"no rows", sql.ErrNoRows, ErrNotFound},
+		{"bad connection", driver.ErrBadConn, ErrConnection},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("refused")}, ErrConnection},
+		{"retryable", &pq.Error{Code: "40001"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.err)
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("wrapError(%v) does not match %v", tt.err, tt.kind)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("wrapError(%v) does not match the original error", tt.err)
+			}
+		})
+	}
+	if !IsRetryable(wrapError(&pq.Error{Code: "40P01"})) {
+		t.Error("wrapped deadlock is not retryable")
+	}
+}
+
+func TestWrapErrorKeepsContextErrors(t *testing.T) {
+	for _, err := range []error{nil, context.Canceled, context.DeadlineExceeded, errors.New("other")} {
+		if got := wrapError(err); got != err {
+			t.Errorf("wrapError(%v) = %v", err, got)
+		}
+	}
+}
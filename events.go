@@ -0,0 +1,123 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+/*
+ListenerEventType - type of listener connection event
+*/
+type ListenerEventType int
+
+const (
+	// ListenerEventConnected - listener connection established for the first time
+	ListenerEventConnected ListenerEventType = iota
+	// ListenerEventDisconnected - listener connection lost, Err holds the reason
+	ListenerEventDisconnected
+	// ListenerEventReconnected - listener connection re-established after a failure
+	ListenerEventReconnected
+	// ListenerEventConnectionAttemptFailed - reconnect attempt failed, Err holds the reason
+	ListenerEventConnectionAttemptFailed
+	// ListenerEventMissedNotifications - connection was re-established, notifications sent meanwhile are lost
+	ListenerEventMissedNotifications
+)
+
+func (t ListenerEventType) String() string {
+	switch t {
+	case ListenerEventConnected:
+		return "connected"
+	case ListenerEventDisconnected:
+		return "disconnected"
+	case ListenerEventReconnected:
+		return "reconnected"
+	case ListenerEventConnectionAttemptFailed:
+		return "connection_attempt_failed"
+	case ListenerEventMissedNotifications:
+		return "missed_notifications"
+	}
+	return "unknown"
+}
+
+/*
+ListenerEvent - listener connection event passed to EventHandler
+*/
+type ListenerEvent struct {
+	Type ListenerEventType
+	Err  error
+}
+
+/*
+ListenerError - error happened while listening: subscribing to channel, handling notification etc.
+*/
+type ListenerError struct {
+	Op      string
+	Channel string
+	Err     error
+}
+
+func (e *ListenerError) Error() string {
+	msg := "pg: listener " + e.Op
+	if e.Channel != "" {
+		msg += " " + e.Channel
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+/*
+SetEventHandler - sets handler for listener connection events. It is called from the listener goroutine
+so it should not block
+*/
+func (pgm *Mapper) SetEventHandler(handler func(ListenerEvent)) {
+	pgm.EventHandler = handler
+}
+
+/*
+SetErrorHandler - sets handler for listener errors. Without it errors are logged
+*/
+func (pgm *Mapper) SetErrorHandler(handler func(error)) {
+	pgm.ErrorHandler = handler
+}
+
+func (pgm *Mapper) emitEvent(event ListenerEvent) {
+	if len(pgm.listenerHooks) > 0 {
+		ctx := pgm.listenContext()
+		for _, hook := range pgm.listenerHooks {
+			hook.OnListenerEvent(ctx, event)
+		}
+	}
+	if pgm.EventHandler != nil {
+		pgm.EventHandler(event)
+	}
+}
+
+func (pgm *Mapper) reportError(err error) {
+	if pgm.ErrorHandler != nil {
+		pgm.ErrorHandler(err)
+		return
+	}
+	pgm.log(context.Background(), LevelError, "listener error", Field{"error", err})
+}
+
+func (pgm *Mapper) listenerCallback(ev pq.ListenerEventType, err error) {
+	event := ListenerEvent{Err: err}
+	switch ev {
+	case pq.ListenerEventConnected:
+		event.Type = ListenerEventConnected
+	case pq.ListenerEventDisconnected:
+		event.Type = ListenerEventDisconnected
+	case pq.ListenerEventReconnected:
+		event.Type = ListenerEventReconnected
+	case pq.ListenerEventConnectionAttemptFailed:
+		event.Type = ListenerEventConnectionAttemptFailed
+	}
+	pgm.emitEvent(event)
+	if err != nil {
+		pgm.reportError(&ListenerError{Op: event.Type.String(), Err: err})
+	}
+}
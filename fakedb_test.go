@@ -0,0 +1,115 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+)
+
+/*
+fakeDriver - database/sql driver accepting every statement: queries return no rows, other
+statements affect one row. Executed statements are recorded
+*/
+type fakeDriver struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, query)
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.driver.record("BEGIN")
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.driver.record("COMMIT")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.driver.record("ROLLBACK")
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.record(s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.record(s.query)
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (fakeRows) Close() error {
+	return nil
+}
+
+func (fakeRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+type fakeConnector struct {
+	driver *fakeDriver
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+/*
+fakeMapper - mapper connected to fakeDriver
+*/
+func fakeMapper(t *testing.T, source string) (*Mapper, *fakeDriver) {
+	d := &fakeDriver{}
+	conn := sql.OpenDB(fakeConnector{d})
+	t.Cleanup(func() { conn.Close() })
+	return &Mapper{Source: source, Conn: conn}, d
+}
@@ -0,0 +1,147 @@
+package pg
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+/*
+DefaultTypeField - payload field used to dispatch notifications by type
+*/
+const DefaultTypeField = "type"
+
+/*
+HandlerFunc - typed notification handler. Payload is a pointer to a new value of the registered target type
+*/
+type HandlerFunc func(channel string, payload interface{}) error
+
+type handlerKey struct {
+	channel     string
+	payloadType string
+}
+
+type handlerEntry struct {
+	target  reflect.Type
+	handler HandlerFunc
+}
+
+type handlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[handlerKey]handlerEntry
+	typed    map[string]int // number of type specific handlers per channel
+}
+
+/*
+Handle - registers handler for all notifications of the channel. Payload is decoded into a new value of target's type
+*/
+func (pgm *Mapper) Handle(channel string, target interface{}, handler HandlerFunc) {
+	pgm.HandleType(channel, "", target, handler)
+}
+
+/*
+HandleType - registers handler for notifications of the channel which payload has TypeField equal to payloadType
+*/
+func (pgm *Mapper) HandleType(channel, payloadType string, target interface{}, handler HandlerFunc) {
+	var t reflect.Type
+	if target != nil {
+		t = reflect.TypeOf(target)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	r := pgm.registry()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := handlerKey{channel, payloadType}
+	if _, ok := r.handlers[key]; !ok && payloadType != "" {
+		r.typed[channel]++
+	}
+	r.handlers[key] = handlerEntry{target: t, handler: handler}
+}
+
+/*
+RemoveHandler - removes handler registered with Handle (empty payloadType) or HandleType
+*/
+func (pgm *Mapper) RemoveHandler(channel, payloadType string) {
+	r := pgm.registry()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := handlerKey{channel, payloadType}
+	if _, ok := r.handlers[key]; !ok {
+		return
+	}
+	delete(r.handlers, key)
+	if payloadType != "" {
+		r.typed[channel]--
+	}
+}
+
+func (pgm *Mapper) registry() *handlerRegistry {
+	pgm.mu.Lock()
+	defer pgm.mu.Unlock()
+	if pgm.handlers == nil {
+		pgm.handlers = &handlerRegistry{
+			handlers: map[handlerKey]handlerEntry{},
+			typed:    map[string]int{},
+		}
+	}
+	return pgm.handlers
+}
+
+func (pgm *Mapper) typeField() string {
+	if pgm.TypeField != "" {
+		return pgm.TypeField
+	}
+	return DefaultTypeField
+}
+
+func (r *handlerRegistry) lookup(channel string, payload []byte, typeField string) (handlerEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.typed[channel] > 0 {
+		var probe map[string]json.RawMessage
+		if json.Unmarshal(payload, &probe) == nil {
+			var payloadType string
+			if json.Unmarshal(probe[typeField], &payloadType) == nil && payloadType != "" {
+				if entry, ok := r.handlers[handlerKey{channel, payloadType}]; ok {
+					return entry, true
+				}
+			}
+		}
+	}
+	entry, ok := r.handlers[handlerKey{channel, ""}]
+	return entry, ok
+}
+
+func (e handlerEntry) decode(payload []byte) (interface{}, error) {
+	if e.target == nil {
+		var data interface{}
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	}
+	v := reflect.New(e.target)
+	if err := json.Unmarshal(payload, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+/*
+handleNotification - decodes payload and dispatches it to registered handler or to Handler/ChannelHandler
+*/
+func (pgm *Mapper) handleNotification(channel string, payload []byte) error {
+	if entry, ok := pgm.registry().lookup(channel, payload, pgm.typeField()); ok {
+		data, err := entry.decode(payload)
+		if err != nil {
+			return err
+		}
+		return entry.handler(channel, data)
+	}
+	var data interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return err
+	}
+	pgm.dispatch(channel, data)
+	return nil
+}
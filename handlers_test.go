@@ -0,0 +1,156 @@
+package pg
+
+import "testing"
+
+type orderCreated struct {
+	ID int64 `json:"id"`
+}
+
+func TestHandleNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(pgm *Mapper, handled *string)
+		channel string
+		payload string
+		want    string
+		err     bool
+	}{
+		{
+			name: "type field matches",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.HandleType("orders", "created", orderCreated{}, func(channel string, payload interface{}) error {
+					*handled = "created"
+					if payload.(*orderCreated).ID != 1 {
+						*handled = "created with wrong payload"
+					}
+					return nil
+				})
+			},
+			channel: "orders",
+			payload: `{"type":"created","id":1}`,
+			want:    "created",
+		},
+		{
+			name: "custom type field",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.TypeField = "kind"
+				pgm.HandleType("orders", "created", nil, recordHandler(handled, "created"))
+			},
+			channel: "orders",
+			payload: `{"type":"deleted","kind":"created"}`,
+			want:    "created",
+		},
+		{
+			name: "unknown type falls back to channel handler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.HandleType("orders", "created", nil, recordHandler(handled, "created"))
+			},
+			channel: "orders",
+			payload: `{"type":"deleted"}`,
+			want:    "channel",
+		},
+		{
+			name: "payload without type falls back to channel handler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.HandleType("orders", "created", nil, recordHandler(handled, "created"))
+			},
+			channel: "orders",
+			payload: `[1, 2]`,
+			want:    "channel",
+		},
+		{
+			name: "other channel falls back to ChannelHandler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.ChannelHandler = func(channel string, data interface{}) { *handled = "ChannelHandler " + channel }
+				pgm.Handler = func(data interface{}) { *handled = "Handler" }
+			},
+			channel: "users",
+			payload: `{"type":"created"}`,
+			want:    "ChannelHandler users",
+		},
+		{
+			name: "falls back to Handler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.HandleType("orders", "created", nil, recordHandler(handled, "created"))
+				pgm.Handler = func(data interface{}) { *handled = "Handler" }
+			},
+			channel: "orders",
+			payload: `{"type":"deleted"}`,
+			want:    "Handler",
+		},
+		{
+			name: "removed type handler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.HandleType("orders", "created", nil, recordHandler(handled, "created"))
+				pgm.RemoveHandler("orders", "created")
+			},
+			channel: "orders",
+			payload: `{"type":"created"}`,
+			want:    "channel",
+		},
+		{
+			name: "removed channel handler",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", nil, recordHandler(handled, "channel"))
+				pgm.RemoveHandler("orders", "")
+				pgm.Handler = func(data interface{}) { *handled = "Handler" }
+			},
+			channel: "orders",
+			payload: `{}`,
+			want:    "Handler",
+		},
+		{
+			name: "payload does not match target",
+			setup: func(pgm *Mapper, handled *string) {
+				pgm.Handle("orders", orderCreated{}, recordHandler(handled, "channel"))
+			},
+			channel: "orders",
+			payload: `{"id":"one"}`,
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgm := &Mapper{}
+			var handled string
+			tt.setup(pgm, &handled)
+			err := pgm.handleNotification(tt.channel, []byte(tt.payload))
+			if (err != nil) != tt.err {
+				t.Fatalf("handleNotification() error = %v, want error %v", err, tt.err)
+			}
+			if handled != tt.want {
+				t.Errorf("handled by %q, want %q", handled, tt.want)
+			}
+		})
+	}
+}
+
+func recordHandler(handled *string, name string) HandlerFunc {
+	return func(channel string, payload interface{}) error {
+		*handled = name
+		return nil
+	}
+}
+
+func TestRemoveHandlerTypedCount(t *testing.T) {
+	pgm := &Mapper{}
+	noop := func(channel string, payload interface{}) error { return nil }
+	pgm.HandleType("orders", "created", nil, noop)
+	pgm.HandleType("orders", "created", nil, noop)
+	pgm.HandleType("orders", "deleted", nil, noop)
+	pgm.RemoveHandler("orders", "created")
+	pgm.RemoveHandler("orders", "created")
+	pgm.RemoveHandler("orders", "unknown")
+	if got := pgm.registry().typed["orders"]; got != 1 {
+		t.Errorf("typed handlers = %d, want 1", got)
+	}
+	pgm.RemoveHandler("orders", "deleted")
+	if got := pgm.registry().typed["orders"]; got != 0 {
+		t.Errorf("typed handlers = %d, want 0", got)
+	}
+}
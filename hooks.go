@@ -0,0 +1,261 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+/*
+QueryEvent - executed statement passed to QueryHook. Argument values are not exposed, only their number
+*/
+type QueryEvent struct {
+	SQL string
+	// Source - table the statement works on: mapper Source or the table given to the call, empty if unknown
+	Source   string
+	ArgCount int
+	Start    time.Time
+	Duration time.Duration
+	// RowsAffected - number of affected rows, -1 for queries returning rows
+	RowsAffected int64
+	Err          error
+
+	args []interface{}
+	inTx bool
+}
+
+/*
+QueryHook - called before and after every statement executed by mapper. Context returned by
+BeforeQuery is used for the statement and passed to AfterQuery
+*/
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+/*
+AddQueryHook - adds hook called for every statement. Hooks should be added before mapper is used
+*/
+func (pgm *Mapper) AddQueryHook(hook QueryHook) {
+	pgm.hooks = append(pgm.hooks, hook)
+}
+
+/*
+beginQuery - starts statement event on source table and calls BeforeQuery hooks
+*/
+func (pgm *Mapper) beginQuery(ctx context.Context, source, SQL string, args []interface{}) (context.Context, *QueryEvent) {
+	event := &QueryEvent{
+		SQL:          SQL,
+		Source:       sourceTable(source),
+		ArgCount:     len(args),
+		Start:        time.Now(),
+		RowsAffected: -1,
+		args:         args,
+		inTx:         pgm.tx != nil,
+	}
+	for _, hook := range pgm.hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+	}
+	return ctx, event
+}
+
+/*
+endQuery - finishes statement event, logs it and calls AfterQuery hooks in reverse order.
+Returns err wrapped into *Error
+*/
+func (pgm *Mapper) endQuery(ctx context.Context, event *QueryEvent, rowsAffected int64, err error) error {
+	err = wrapError(err)
+	event.Duration = time.Since(event.Start)
+	event.RowsAffected = rowsAffected
+	event.Err = err
+	pgm.logQuery(ctx, event)
+	for i := len(pgm.hooks) - 1; i >= 0; i-- {
+		pgm.hooks[i].AfterQuery(ctx, event)
+	}
+	return err
+}
+
+/*
+sourceTable - table of "table alias" or "table JOIN ..." source
+*/
+func sourceTable(source string) string {
+	if fields := strings.Fields(source); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func affectedRows(result sql.Result) int64 {
+	if result == nil {
+		return -1
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+/*
+SlowQuery - statement reported by slow query reporter
+*/
+type SlowQuery struct {
+	SQL      string
+	Source   string
+	ArgCount int
+	Duration time.Duration
+	Err      error
+	// Plan - EXPLAIN output, empty if explain is disabled, skipped or failed
+	Plan       string
+	ExplainErr error
+}
+
+/*
+MaxSlowQueryExplains - number of EXPLAIN statements slow query reporter runs at once. Slow queries
+arriving while all of them are busy are reported without plan, so explaining does not load the
+connection pool when it is already saturated
+*/
+const MaxSlowQueryExplains = 2
+
+/*
+ReportSlowQueries - adds hook reporting statements running longer than threshold. With explain
+the statement is explained in background and its plan is added to the report. Statements executed
+in a transaction are not explained as they may use objects visible only inside it. Without report func
+slow queries are logged with warn level
+*/
+func (pgm *Mapper) ReportSlowQueries(threshold time.Duration, explain bool, report func(SlowQuery)) {
+	pgm.AddQueryHook(&slowQueryHook{
+		mapper:     pgm,
+		threshold:  threshold,
+		explain:    explain,
+		report:     report,
+		explaining: make(chan struct{}, MaxSlowQueryExplains),
+	})
+}
+
+type slowQueryHook struct {
+	mapper     *Mapper
+	threshold  time.Duration
+	explain    bool
+	report     func(SlowQuery)
+	explaining chan struct{}
+}
+
+func (h *slowQueryHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *slowQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Duration < h.threshold {
+		return
+	}
+	slow := SlowQuery{
+		SQL:      event.SQL,
+		Source:   event.Source,
+		ArgCount: event.ArgCount,
+		Duration: event.Duration,
+		Err:      event.Err,
+	}
+	if !h.explain || event.inTx || !explainable(event.SQL) {
+		h.emit(ctx, slow)
+		return
+	}
+	select {
+	case h.explaining <- struct{}{}:
+	default:
+		h.emit(ctx, slow)
+		return
+	}
+	go func() {
+		defer func() { <-h.explaining }()
+		slow.Plan, slow.ExplainErr = h.mapper.explain(event.SQL, event.args)
+		h.emit(context.Background(), slow)
+	}()
+}
+
+func (h *slowQueryHook) emit(ctx context.Context, slow SlowQuery) {
+	if h.report != nil {
+		h.report(slow)
+		return
+	}
+	fields := []Field{{"sql", slow.SQL}, {"args", slow.ArgCount}, {"duration", slow.Duration}}
+	if slow.Plan != "" {
+		fields = append(fields, Field{"plan", slow.Plan})
+	}
+	h.mapper.log(ctx, LevelWarn, "slow query", fields...)
+}
+
+/*
+explain - returns EXPLAIN output of the statement. It is executed directly on the connection pool
+so it is not reported to hooks
+*/
+func (pgm *Mapper) explain(SQL string, args []interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rows, err := pgm.Conn.QueryContext(ctx, "EXPLAIN "+SQL, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+func explainable(SQL string) bool {
+	fields := strings.Fields(SQL)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
+
+/*
+NotificationEvent - handled notification passed to ListenerHook
+*/
+type NotificationEvent struct {
+	Channel  string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+/*
+ListenerHook - called for listener connection events and for every handled notification.
+ctx is the context of ListenContext
+*/
+type ListenerHook interface {
+	OnListenerEvent(ctx context.Context, event ListenerEvent)
+	BeforeNotification(ctx context.Context, event *NotificationEvent) context.Context
+	AfterNotification(ctx context.Context, event *NotificationEvent)
+}
+
+/*
+AddListenerHook - adds listener hook. Hooks should be added before Listen is called
+*/
+func (pgm *Mapper) AddListenerHook(hook ListenerHook) {
+	pgm.listenerHooks = append(pgm.listenerHooks, hook)
+}
+
+/*
+listenContext - returns context of running ListenContext
+*/
+func (pgm *Mapper) listenContext() context.Context {
+	pgm.mu.Lock()
+	defer pgm.mu.Unlock()
+	if pgm.listenCtx == nil {
+		return context.Background()
+	}
+	return pgm.listenCtx
+}
@@ -0,0 +1,135 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type orderHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h orderHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return ctx
+}
+
+func (h orderHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	*h.calls = append(*h.calls, "after "+h.name)
+}
+
+func TestQueryHooksOrder(t *testing.T) {
+	var calls []string
+	pgm := &Mapper{Source: "users"}
+	pgm.AddQueryHook(orderHook{"a", &calls})
+	pgm.AddQueryHook(orderHook{"b", &calls})
+	ctx, event := pgm.beginQuery(context.Background(), pgm.Source, "SELECT $1", []interface{}{1})
+	err := pgm.endQuery(ctx, event, 3, &pq.Error{Code: "23505", Constraint: "users_email_key"})
+
+	want := []string{"before a", "before b", "after b", "after a"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+	if event.ArgCount != 1 || event.RowsAffected != 3 || event.Source != "users" {
+		t.Errorf("event = %+v", event)
+	}
+	if !errors.Is(err, ErrUniqueViolation) || !errors.Is(event.Err, ErrUniqueViolation) {
+		t.Errorf("err = %v, event.Err = %v, want wrapped unique violation", err, event.Err)
+	}
+}
+
+func slowEvent(inTx bool) *QueryEvent {
+	return &QueryEvent{SQL: "SELECT 1", Duration: time.Second, inTx: inTx}
+}
+
+func TestSlowQueryHookSkipsExplainInTx(t *testing.T) {
+	var reports []SlowQuery
+	pgm := &Mapper{}
+	pgm.ReportSlowQueries(time.Millisecond, true, func(slow SlowQuery) {
+		reports = append(reports, slow)
+	})
+	hook := pgm.hooks[0]
+	hook.AfterQuery(context.Background(), &QueryEvent{SQL: "SELECT 1", Duration: time.Microsecond})
+	hook.AfterQuery(context.Background(), slowEvent(true))
+	if len(reports) != 1 || reports[0].Plan != "" || reports[0].ExplainErr != nil {
+		t.Fatalf("reports = %+v, want one report without plan", reports)
+	}
+}
+
+func TestSlowQueryHookDropsExplainWhenBusy(t *testing.T) {
+	var reports []SlowQuery
+	pgm := &Mapper{}
+	pgm.ReportSlowQueries(time.Millisecond, true, func(slow SlowQuery) {
+		reports = append(reports, slow)
+	})
+	hook := pgm.hooks[0].(*slowQueryHook)
+	for i := 0; i < MaxSlowQueryExplains; i++ {
+		hook.explaining <- struct{}{}
+	}
+	hook.AfterQuery(context.Background(), slowEvent(false))
+	if len(reports) != 1 || reports[0].Plan != "" {
+		t.Fatalf("reports = %+v, want one report without plan", reports)
+	}
+}
+
+type sourceHook struct {
+	sources *[]string
+}
+
+func (h sourceHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	*h.sources = append(*h.sources, event.Source)
+	return ctx
+}
+
+func (h sourceHook) AfterQuery(ctx context.Context, event *QueryEvent) {}
+
+func TestQueryEventSource(t *testing.T) {
+	pgm, _ := fakeMapper(t, "users")
+	var sources []string
+	pgm.AddQueryHook(sourceHook{&sources})
+
+	rows, err := pgm.Load("orders o", "*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var dest []struct {
+		ID int64 `db:"id"`
+	}
+	if err := pgm.LoadInto(&dest, "invoices", nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = pgm.Select().From("payments p").Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	rows, err = pgm.Exec("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := pgm.Create([]string{"id"}, []interface{}{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"orders", "invoices", "payments", "users", "users"}
+	if len(sources) != len(want) {
+		t.Fatalf("sources = %v, want %v", sources, want)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Fatalf("sources = %v, want %v", sources, want)
+		}
+	}
+}
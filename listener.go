@@ -0,0 +1,349 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*
+DefaultListenIdleTimeout - idle time after which listener connection is pinged if ListenIdleTimeout is not set
+*/
+const DefaultListenIdleTimeout = 90 * time.Second
+
+/*
+ErrNoChannels - returned by Listen when there is no channel to subscribe to
+*/
+var ErrNoChannels = errors.New("pg: no channels to listen")
+
+/*
+ErrListening - returned by Listen when mapper is already listening
+*/
+var ErrListening = errors.New("pg: mapper is already listening")
+
+/*
+Listen - subscribes to channels (in addition to the ones added with Subscribe) and handles notifications.
+Blocks until StopListen or Close is called
+*/
+func (pgm *Mapper) Listen(channels ...string) error {
+	return pgm.ListenContext(context.Background(), channels...)
+}
+
+/*
+ListenContext - same as Listen but returns when ctx is cancelled. Before returning listener is closed
+(which drops all its channels) and in-flight handlers are finished
+*/
+func (pgm *Mapper) ListenContext(ctx context.Context, channels ...string) error {
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	pgm.mu.Lock()
+	if pgm.Listener != nil {
+		pgm.mu.Unlock()
+		return ErrListening
+	}
+	for _, channel := range channels {
+		pgm.addChannel(channel)
+	}
+	subscribed := append([]string(nil), pgm.channels...)
+	if len(subscribed) == 0 {
+		pgm.mu.Unlock()
+		return ErrNoChannels
+	}
+
+	pgm.log(ctx, LevelInfo, "listener connecting", Field{"channels", subscribed})
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	pgm.stopListen = cancel
+	pgm.listenDone = done
+	pgm.listenCtx = ctx
+	l := pq.NewListener(pgm.ConnectionInfo, 10*time.Second, time.Minute, pgm.listenerCallback)
+	pgm.Listener = l
+	if pgm.WorkerPool.Workers > 0 {
+		pgm.pool = newWorkerPool(pgm, pgm.WorkerPool)
+	}
+	pgm.mu.Unlock()
+
+	defer close(done)
+	defer cancel()
+	if err := listenAll(ctx, l, subscribed); err != nil {
+		pgm.closeListener()
+		return err
+	}
+	if pgm.Outbox != nil {
+		if err := pgm.startOutbox(ctx, pgm.pool); err != nil {
+			pgm.reportError(&ListenerError{Op: "replay", Err: err})
+		}
+	}
+	for pgm.handleListen(ctx) {
+	}
+	pgm.log(context.Background(), LevelInfo, "listener stopping")
+	if err := pgm.closeListener(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+/*
+listenAll - subscribes listener to channels. pq waits for the connection before LISTEN and ignores ctx,
+so listener is closed on cancel to make pending Listen return
+*/
+func listenAll(ctx context.Context, l *pq.Listener, channels []string) error {
+	subscribed := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Close()
+			closed <- true
+		case <-subscribed:
+			closed <- false
+		}
+	}()
+	var err error
+	for _, channel := range channels {
+		if listenErr := l.Listen(channel); listenErr != nil {
+			err = &ListenerError{Op: "listen", Channel: channel, Err: listenErr}
+			break
+		}
+	}
+	close(subscribed)
+	if <-closed {
+		return ctx.Err()
+	}
+	return err
+}
+
+/*
+StopListen - stops running Listen/ListenContext and waits until it returns
+*/
+func (pgm *Mapper) StopListen() {
+	pgm.mu.Lock()
+	stop, done := pgm.stopListen, pgm.listenDone
+	pgm.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+func (pgm *Mapper) closeListener() error {
+	pgm.mu.Lock()
+	l, pool := pgm.Listener, pgm.pool
+	pgm.Listener = nil
+	pgm.pool = nil
+	pgm.stopListen = nil
+	pgm.listenCtx = nil
+	pgm.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	// Notify is not read anymore: drain it, otherwise pq reader may block on the full channel and never
+	// read replies, so Close hangs. Closing the connection drops its LISTENs, no need to UNLISTEN
+	go func() {
+		for range l.Notify {
+		}
+	}()
+	err := l.Close()
+	if errors.Is(err, net.ErrClosed) {
+		// already closed by listenAll on cancel
+		err = nil
+	}
+	if pool != nil {
+		pool.stop()
+	}
+	pgm.inflight.Wait()
+	return err
+}
+
+/*
+Subscribe - adds channels to listen. If listener is already running channels are subscribed immediately
+*/
+func (pgm *Mapper) Subscribe(channels ...string) error {
+	// pq.Listener.Listen blocks while the connection is down, mu must not be held meanwhile
+	// as the reconnect callback and notification handling need it
+	pgm.mu.Lock()
+	l := pgm.Listener
+	var added []string
+	for _, channel := range channels {
+		if pgm.addChannel(channel) {
+			added = append(added, channel)
+		}
+	}
+	pgm.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	for i, channel := range added {
+		if err := l.Listen(channel); err != nil {
+			pgm.mu.Lock()
+			for _, c := range added[i:] {
+				pgm.removeChannel(c)
+			}
+			pgm.mu.Unlock()
+			return &ListenerError{Op: "listen", Channel: channel, Err: err}
+		}
+	}
+	return nil
+}
+
+/*
+Unsubscribe - stops listening to channels
+*/
+func (pgm *Mapper) Unsubscribe(channels ...string) error {
+	pgm.mu.Lock()
+	l := pgm.Listener
+	var removed []string
+	for _, channel := range channels {
+		if pgm.removeChannel(channel) {
+			removed = append(removed, channel)
+		}
+	}
+	pgm.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	for _, channel := range removed {
+		if err := l.Unlisten(channel); err != nil {
+			return &ListenerError{Op: "unlisten", Channel: channel, Err: err}
+		}
+	}
+	return nil
+}
+
+/*
+Channels - returns list of subscribed channels
+*/
+func (pgm *Mapper) Channels() []string {
+	pgm.mu.Lock()
+	defer pgm.mu.Unlock()
+	return append([]string(nil), pgm.channels...)
+}
+
+func (pgm *Mapper) addChannel(channel string) bool {
+	for _, c := range pgm.channels {
+		if c == channel {
+			return false
+		}
+	}
+	pgm.channels = append(pgm.channels, channel)
+	return true
+}
+
+func (pgm *Mapper) removeChannel(channel string) bool {
+	for i, c := range pgm.channels {
+		if c == channel {
+			pgm.channels = append(pgm.channels[:i], pgm.channels[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+/*
+HandleListen - waits for a single notification (or idle timeout) and handles it
+*/
+func (mapper *Mapper) HandleListen() {
+	mapper.handleListen(context.Background())
+}
+
+/*
+handleListen - handles single notification. Returns false when listening should stop
+*/
+func (mapper *Mapper) handleListen(ctx context.Context) bool {
+	mapper.mu.Lock()
+	l, pool := mapper.Listener, mapper.pool
+	mapper.mu.Unlock()
+	if l == nil {
+		return false
+	}
+	timeout := mapper.ListenIdleTimeout
+	if timeout <= 0 {
+		timeout = DefaultListenIdleTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case n, ok := <-l.Notify:
+		if !ok {
+			return false
+		}
+		if n == nil {
+			// pq sends nil after reconnect: notifications sent while disconnected are lost
+			mapper.log(ctx, LevelWarn, "listener reconnected, notifications may have been missed")
+			mapper.emitEvent(ListenerEvent{Type: ListenerEventMissedNotifications})
+			if err := mapper.replayOutbox(ctx, pool); err != nil {
+				mapper.reportError(&ListenerError{Op: "replay", Err: err})
+			}
+			return true
+		}
+		job := notificationJob{channel: n.Channel, payload: []byte(n.Extra)}
+		if !mapper.acceptNotification(&job) {
+			return true
+		}
+		if pool != nil {
+			pool.submit(ctx, job)
+		} else {
+			mapper.processNotification(job)
+		}
+		return true
+	case <-timer.C:
+		mapper.log(ctx, LevelDebug, "no notifications received, checking connection", Field{"idle", timeout})
+		go func() {
+			if err := l.Ping(); err != nil {
+				mapper.reportError(&ListenerError{Op: "ping", Err: err})
+			}
+		}()
+		return true
+	}
+}
+
+func (mapper *Mapper) processNotification(job notificationJob) {
+	mapper.inflight.Add(1)
+	defer mapper.inflight.Done()
+	defer mapper.eventDone(job)
+
+	channel, payload := job.channel, job.payload
+	ctx := mapper.listenContext()
+	event := &NotificationEvent{Channel: channel, Start: time.Now()}
+	for _, hook := range mapper.listenerHooks {
+		ctx = hook.BeforeNotification(ctx, event)
+	}
+	err := mapper.handleNotification(channel, payload)
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	for i := len(mapper.listenerHooks) - 1; i >= 0; i-- {
+		mapper.listenerHooks[i].AfterNotification(ctx, event)
+	}
+	if err != nil {
+		mapper.reportError(&ListenerError{Op: "handle", Channel: channel, Err: err})
+	}
+}
+
+func (mapper *Mapper) dispatch(channel string, data interface{}) {
+	if mapper.ChannelHandler != nil {
+		mapper.ChannelHandler(channel, data)
+		return
+	}
+	if mapper.Handler != nil {
+		mapper.Handler(data)
+	}
+}
+
+func (mapper *Mapper) SetHandler(handler func(interface{})) {
+	mapper.Handler = handler
+}
+
+/*
+SetChannelHandler - sets handler that receives channel name along with notification payload
+*/
+func (mapper *Mapper) SetChannelHandler(handler func(channel string, data interface{})) {
+	mapper.ChannelHandler = handler
+}
@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func unreachableMapper() *Mapper {
+	return &Mapper{DBConfig: DBConfig{
+		User:     "postgres",
+		Host:     "127.0.0.1",
+		Port:     "1",
+		Database: "postgres",
+		SSLmode:  "disable",
+	}}
+}
+
+func TestListenContextCancelWhileConnecting(t *testing.T) {
+	pgm := unreachableMapper()
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- pgm.ListenContext(ctx, "events")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ListenContext() = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenContext did not return after cancel")
+	}
+	if pgm.Listener != nil {
+		t.Error("Listener is not reset")
+	}
+}
+
+func TestStopListenWhileConnecting(t *testing.T) {
+	pgm := unreachableMapper()
+	result := make(chan error, 1)
+	go func() {
+		result <- pgm.Listen("events")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		pgm.StopListen()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StopListen did not return")
+	}
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Listen() = %v, want context.Canceled", err)
+	}
+}
+
+func TestSubscribeWhileConnecting(t *testing.T) {
+	pgm := unreachableMapper()
+	go pgm.Listen("events")
+	defer pgm.StopListen()
+	time.Sleep(100 * time.Millisecond)
+
+	go pgm.Subscribe("other")
+	time.Sleep(50 * time.Millisecond)
+	channels := make(chan []string, 1)
+	go func() {
+		channels <- pgm.Channels()
+	}()
+	select {
+	case got := <-channels:
+		if len(got) != 2 {
+			t.Errorf("Channels() = %v, want 2 channels", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Channels blocked by pending Subscribe")
+	}
+}
@@ -0,0 +1,160 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+/*
+LOG - logging type
+
+Deprecated: use Logger with LevelInfo
+*/
+const LOG = "log"
+
+/*
+ERROR - logging type
+
+Deprecated: use Logger with LevelError
+*/
+const ERROR = "error"
+
+/*
+Level - logging level
+*/
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+/*
+Field - structured logging field
+*/
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+/*
+Logger - receives mapper logs. Mapper without Logger does not log anything
+*/
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...Field)
+}
+
+/*
+LoggerFunc - function implementing Logger
+*/
+type LoggerFunc func(ctx context.Context, level Level, msg string, fields ...Field)
+
+/*
+Log - calls f
+*/
+func (f LoggerFunc) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	f(ctx, level, msg, fields...)
+}
+
+/*
+NewSlogLogger - Logger writing to slog.Logger
+*/
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, level Level, msg string, fields ...Field) {
+		attrs := make([]slog.Attr, len(fields))
+		for i, f := range fields {
+			attrs[i] = slog.Any(f.Key, f.Value)
+		}
+		logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+	})
+}
+
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+/*
+log - logs message with host, database and source fields
+*/
+func (pgm *Mapper) log(ctx context.Context, level Level, msg string, fields ...Field) {
+	if pgm.Logger == nil {
+		return
+	}
+	fields = append([]Field{
+		{"host", pgm.DBConfig.Host},
+		{"database", pgm.DBConfig.Database},
+		{"source", pgm.Source},
+	}, fields...)
+	pgm.Logger.Log(ctx, level, msg, fields...)
+}
+
+/*
+Log - logs data through Logger. First element is logging type (LOG or ERROR), the rest is the message.
+Returns error with the first element as before
+
+Deprecated: set Logger, mapper logs through it
+*/
+func (pgm *Mapper) Log(data ...interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	kind := fmt.Sprint(data[0])
+	level := LevelInfo
+	if kind == ERROR {
+		level = LevelError
+	}
+	var message []string
+	for _, d := range data[1:] {
+		if d != nil {
+			message = append(message, fmt.Sprint(d))
+		}
+	}
+	pgm.log(context.Background(), level, strings.Join(message, " "))
+	return errors.New(kind)
+}
+
+/*
+logQuery - logs executed statement. Arguments are never logged, only their number
+*/
+func (pgm *Mapper) logQuery(ctx context.Context, event *QueryEvent) {
+	if pgm.Logger == nil {
+		return
+	}
+	fields := []Field{
+		{"sql", event.SQL},
+		{"args", event.ArgCount},
+		{"duration", event.Duration},
+	}
+	if event.Err != nil {
+		pgm.log(ctx, LevelError, "query failed", append(fields, Field{"error", event.Err})...)
+		return
+	}
+	pgm.log(ctx, LevelDebug, "query", fields...)
+}
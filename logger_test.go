@@ -0,0 +1,49 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+type logEntry struct {
+	level  Level
+	msg    string
+	fields []Field
+}
+
+func recordLogs(pgm *Mapper) *[]logEntry {
+	var entries []logEntry
+	pgm.Logger = LoggerFunc(func(ctx context.Context, level Level, msg string, fields ...Field) {
+		entries = append(entries, logEntry{level, msg, fields})
+	})
+	return &entries
+}
+
+func TestLogShim(t *testing.T) {
+	pgm := &Mapper{DBConfig: DBConfig{Host: "db", Database: "app"}}
+	entries := recordLogs(pgm)
+	if err := pgm.Log(ERROR, "processing failed:", "bad json", nil); err == nil || err.Error() != ERROR {
+		t.Errorf("Log() = %v, want %q error", err, ERROR)
+	}
+	pgm.Log(LOG, "listening")
+	if len(*entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(*entries))
+	}
+	if e := (*entries)[0]; e.level != LevelError || e.msg != "processing failed: bad json" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e := (*entries)[1]; e.level != LevelInfo || e.msg != "listening" || e.fields[0] != (Field{"host", "db"}) {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestLogQueryOmitsArguments(t *testing.T) {
+	pgm := &Mapper{}
+	entries := recordLogs(pgm)
+	pgm.logQuery(context.Background(), &QueryEvent{SQL: "SELECT $1", ArgCount: 1, args: []interface{}{"secret"}})
+	for _, f := range (*entries)[0].fields {
+		if f.Value == "secret" {
+			t.Fatalf("argument value is logged in field %s", f.Key)
+		}
+	}
+}
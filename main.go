@@ -1,12 +1,15 @@
 package pg
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
@@ -15,16 +18,6 @@ import (
 
 const driverName = "postgres"
 
-/*
-LOG - logging type
-*/
-const LOG = "log"
-
-/*
-ERROR - logging type
-*/
-const ERROR = "error"
-
 /*
 DBConfig - Postgres config
 */
@@ -35,6 +28,15 @@ type DBConfig struct {
 	Port,
 	Database,
 	SSLmode string
+	// StatementTimeout - server side statement_timeout of every connection
+	StatementTimeout time.Duration
+	// ApplicationName - application_name reported in pg_stat_activity
+	ApplicationName string
+	// connection pool limits, zero keeps database/sql defaults (MaxIdleConns is 2, others are unlimited)
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 /*
@@ -47,8 +49,35 @@ type Mapper struct {
 	Source            string
 	ConnectionInfo    string
 	ListenIdleTimeout time.Duration
+	QueryTimeout      time.Duration // timeout of queries read by the mapper, rows of Exec, Load and Query are limited by ctx
 	Handler           func(interface{})
-	Logger            func(...interface{}) error
+	ChannelHandler    func(channel string, data interface{})
+	TypeField         string
+	EventHandler      func(ListenerEvent)
+	ErrorHandler      func(error)
+	WorkerPool        WorkerPoolConfig
+	Outbox            *OutboxConfig
+	StrictScan        bool
+	BatchProgress     func(BatchProgress)
+	CopyThreshold     int // InsertBatch of more rows uses COPY, disabled if zero
+	Logger            Logger
+
+	mu         sync.Mutex
+	channels   []string
+	handlers   *handlerRegistry
+	pool       *workerPool
+	stopListen context.CancelFunc
+	listenDone chan struct{}
+	inflight   sync.WaitGroup
+
+	events eventLog
+
+	tx      *sql.Tx
+	txLevel int
+	hooks   []QueryHook
+
+	listenerHooks []ListenerHook
+	listenCtx     context.Context
 }
 
 /*
@@ -64,91 +93,217 @@ func (pgm *Mapper) connect() error {
 		dbConfig.Database,
 		dbConfig.SSLmode,
 	)
+	if dbConfig.StatementTimeout > 0 {
+		pgm.ConnectionInfo += "&statement_timeout=" + strconv.FormatInt(int64(dbConfig.StatementTimeout/time.Millisecond), 10)
+	}
+	if dbConfig.ApplicationName != "" {
+		pgm.ConnectionInfo += "&application_name=" + url.QueryEscape(dbConfig.ApplicationName)
+	}
 	conn, err := sql.Open(driverName, pgm.ConnectionInfo)
 	if err != nil {
-		fmt.Println("Connection error: ", err)
+		pgm.log(context.Background(), LevelError, "connection failed", Field{"error", err})
 		return err
 	}
 	if conn == nil {
-		return pgm.Log(ERROR, "Connection to PostgreSQL is nil", nil, nil)
+		return errors.New("pg: connection to PostgreSQL is nil")
+	}
+	if dbConfig.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	}
+	if dbConfig.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	}
+	if dbConfig.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	}
+	if dbConfig.ConnMaxIdleTime > 0 {
+		conn.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime)
 	}
 	pgm.Conn = conn
 	return nil
 }
 
 /*
-Load - selecting data from DB
+Stats - connection pool statistics, empty if not connected yet
 */
-func (pgm *Mapper) Load(source string, fields string, query interface{}) (*sql.Rows, error) {
-	if err := pgm.checkConnection(); err != nil {
-		return nil, err
+func (pgm *Mapper) Stats() sql.DBStats {
+	if pgm.Conn == nil {
+		return sql.DBStats{}
 	}
+	return pgm.Conn.Stats()
+}
 
+/*
+Load - selecting data from DB. query is either a condition with $n placeholders bound to args
+(e.g. "id = $1 AND status = $2", id, status) or Where
+*/
+func (pgm *Mapper) Load(source string, fields string, query interface{}, args ...interface{}) (*sql.Rows, error) {
+	return pgm.LoadContext(context.Background(), source, fields, query, args...)
+}
+
+/*
+LoadContext - Load with context
+*/
+func (pgm *Mapper) LoadContext(ctx context.Context, source string, fields string, query interface{}, args ...interface{}) (*sql.Rows, error) {
+	condition, args, err := buildCondition(query, args)
+	if err != nil {
+		return nil, err
+	}
 	SQL := "SELECT " + fields + " FROM " + source
-	if query != nil {
-		SQL += " WHERE " + query.(string)
+	if condition != "" {
+		SQL += " WHERE " + condition
 	}
 	SQL += ";"
-	// fmt.Println(SQL)
-	rows, err := pgm.Exec(SQL)
-	if err != nil {
-		return rows, err
-	}
-	return rows, nil
+	return pgm.query(ctx, source, SQL, args)
 }
 
 /*
-Save — method inserts in DB row on duplicate key updates fields
+Save — method inserts in DB row on duplicate key updates fields. key holds conflict columns
+(values are not used), they are sorted to produce the same statement every time and are not updated
 */
 func (pgm *Mapper) Save(fields []string, values []interface{}, key map[string]interface{}) error {
-	SQL := pgm.generateInsertQuery(fields)
-	SQL += pgm.generateOnConflictQuery(fields, key)
-	return pgm.execute(SQL, values)
+	return pgm.SaveContext(context.Background(), fields, values, key)
+}
+
+/*
+SaveContext - Save with context
+*/
+func (pgm *Mapper) SaveContext(ctx context.Context, fields []string, values []interface{}, key map[string]interface{}) error {
+	var columns []string
+	for column := range key {
+		columns = append(columns, column)
+	}
+	return pgm.UpsertContext(ctx, fields, values, UpsertOptions{ConflictColumns: sortedStrings(columns)})
 }
 
 /*
 Create - creating new row in DB. Does not updates on conflict
 */
 func (pgm *Mapper) Create(fields []string, values []interface{}) error {
+	return pgm.CreateContext(context.Background(), fields, values)
+}
+
+/*
+CreateContext - Create with context
+*/
+func (pgm *Mapper) CreateContext(ctx context.Context, fields []string, values []interface{}) error {
 	SQL := pgm.generateInsertQuery(fields)
-	return pgm.execute(SQL, values)
+	return pgm.execute(ctx, SQL, values)
+}
+
+func (pgm *Mapper) execute(ctx context.Context, SQL string, values []interface{}) error {
+	_, err := pgm.executeResult(ctx, SQL, values)
+	return err
 }
 
-func (pgm *Mapper) execute(SQL string, values []interface{}) error {
+func (pgm *Mapper) executeResult(ctx context.Context, SQL string, values []interface{}) (sql.Result, error) {
 	if err := pgm.checkConnection(); err != nil {
-		return err
+		return nil, err
 	}
+	ctx, cancel := pgm.withTimeout(ctx)
+	defer cancel()
 
-	stmt, err := pgm.Conn.Prepare(SQL)
+	ctx, event := pgm.beginQuery(ctx, pgm.Source, SQL, values)
+	stmt, err := pgm.db().PrepareContext(ctx, SQL)
 	if err != nil {
-		fmt.Println("Preparing statement error: ", err, SQL)
-		return err
+		return nil, pgm.endQuery(ctx, event, -1, err)
 	}
 	defer stmt.Close()
-	_, execErr := stmt.Exec(values...)
-	if execErr != nil {
-		fmt.Println("Exec error: ", execErr)
-		return execErr
+	result, err := stmt.ExecContext(ctx, values...)
+	err = pgm.endQuery(ctx, event, affectedRows(result), err)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return result, nil
 }
 
 /*
-Exec - executing prepared SQL string
+execDirect - executes statement on source table without preparing it, e.g. several statements or DDL
 */
-func (pgm *Mapper) Exec(SQL string) (*sql.Rows, error) {
+func (pgm *Mapper) execDirect(ctx context.Context, source, SQL string, args ...interface{}) (sql.Result, error) {
+	ctx, event := pgm.beginQuery(ctx, source, SQL, args)
+	result, err := pgm.db().ExecContext(ctx, SQL, args...)
+	return result, pgm.endQuery(ctx, event, affectedRows(result), err)
+}
+
+/*
+Exec - executing SQL string with bound arguments
+*/
+func (pgm *Mapper) Exec(SQL string, args ...interface{}) (*sql.Rows, error) {
+	return pgm.ExecContext(context.Background(), SQL, args...)
+}
+
+/*
+ExecContext - Exec with context. ctx limits both the query and reading of the rows, QueryTimeout is not applied
+*/
+func (pgm *Mapper) ExecContext(ctx context.Context, SQL string, args ...interface{}) (*sql.Rows, error) {
+	return pgm.query(ctx, pgm.Source, SQL, args)
+}
+
+/*
+query - runs statement returning rows on source table. Rows are read after return, so timeout is up to ctx
+*/
+func (pgm *Mapper) query(ctx context.Context, source, SQL string, args []interface{}) (*sql.Rows, error) {
 	if err := pgm.checkConnection(); err != nil {
 		return nil, err
 	}
-	return pgm.Conn.Query(SQL)
+	ctx, event := pgm.beginQuery(ctx, source, SQL, args)
+	rows, err := pgm.db().QueryContext(ctx, SQL, args...)
+	return rows, pgm.endQuery(ctx, event, -1, err)
 }
 
 func (pgm *Mapper) checkConnection() error {
-	if pgm.Conn == nil {
+	if pgm.tx == nil && pgm.Conn == nil {
 		return pgm.connect()
 	}
 	return nil
 }
+
+/*
+quoteIdentifier - quotes identifier written as in SQL, schema qualified names are quoted part by part.
+This is the one quoting rule of the package: unquoted names are lower cased like PostgreSQL folds them,
+names in double quotes are kept exactly, so quoting never changes which column a name refers to
+*/
+func quoteIdentifier(name string) string {
+	parts := splitIdentifier(name)
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(resolveName(part))
+	}
+	return strings.Join(parts, ".")
+}
+
+/*
+resolveName - returns name of SQL identifier: quoted identifier is unquoted, unquoted is lower cased
+*/
+func resolveName(identifier string) string {
+	identifier = strings.TrimSpace(identifier)
+	if len(identifier) >= 2 && identifier[0] == '"' && identifier[len(identifier)-1] == '"' {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	}
+	return strings.ToLower(identifier)
+}
+
+/*
+splitIdentifier - splits schema qualified name by dots outside of double quotes
+*/
+func splitIdentifier(name string) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '"':
+			quoted = !quoted
+		case '.':
+			if !quoted {
+				parts = append(parts, name[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
+
 func (pgm *Mapper) generateInsertQuery(fields []string) string {
 	SQL := "INSERT INTO " + pgm.Source + " (" + strings.Join(fields, ",") + ") VALUES "
 	var placeholder []string
@@ -160,32 +315,65 @@ func (pgm *Mapper) generateInsertQuery(fields []string) string {
 	SQL += "(" + strings.Join(placeholder, ",") + ")"
 	return SQL
 }
-func (pgm *Mapper) generateOnConflictQuery(fields []string, keys map[string]interface{}) string {
-	if len(keys) == 0 {
+func (pgm *Mapper) generateOnConflictQuery(fields []string, options UpsertOptions) string {
+	var target string
+	switch {
+	case options.Constraint != "":
+		target = " ON CONSTRAINT " + options.Constraint
+	case len(options.ConflictColumns) > 0:
+		target = " (" + strings.Join(options.ConflictColumns, ",") + ")"
+	default:
 		return " ON CONFLICT DO NOTHING "
 	}
-	var idx []string
-	for key := range keys {
-		idx = append(idx, key)
+	update := options.UpdateColumns
+	if update == nil {
+		for _, field := range fields {
+			if !contains(options.ConflictColumns, field) {
+				update = append(update, field)
+			}
+		}
 	}
-	SQL := " ON CONFLICT (" + strings.Join(idx, ",") + ") DO UPDATE SET "
-	var placeholder []string
-	for i, field := range fields {
-		key := strconv.Itoa((i + 1))
-		value := field + " = $" + key + " "
-		placeholder = append(placeholder, value)
+	if len(update) == 0 {
+		return " ON CONFLICT" + target + " DO NOTHING "
+	}
+	var set []string
+	for _, field := range update {
+		set = append(set, field+" = EXCLUDED."+field)
+	}
+	SQL := " ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(set, ",")
+	if options.Where != "" {
+		SQL += " WHERE " + rebind(options.Where, len(fields))
 	}
-	SQL += strings.Join(placeholder, ",")
 	return SQL
 }
 
-func (pgm *Mapper) InsertBatch(fields []string, rows []interface{}, onDuplicate interface{}) error {
-	if len(rows) == 0 {
-		return nil
-	}
-	if err := pgm.checkConnection(); err != nil {
-		return err
+func sortedStrings(list []string) []string {
+	sort.Strings(list)
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
+	return false
+}
+
+func (pgm *Mapper) InsertBatch(fields []string, rows []interface{}, onDuplicate interface{}) error {
+	return pgm.InsertBatchContext(context.Background(), fields, rows, onDuplicate)
+}
+
+/*
+InsertBatchContext - InsertBatch with context
+*/
+func (pgm *Mapper) InsertBatchContext(ctx context.Context, fields []string, rows []interface{}, onDuplicate interface{}) error {
+	_, err := pgm.InsertBatchAffectedContext(ctx, fields, rows, onDuplicate)
+	return err
+}
+
+func (pgm *Mapper) generateBatchQuery(fields []string, rows []interface{}, onDuplicate interface{}) (string, []interface{}) {
 	var values = []interface{}{}
 	SQL := "insert into " + pgm.Source + " (" + strings.Join(fields, ",") + ") values "
 
@@ -203,76 +391,10 @@ func (pgm *Mapper) InsertBatch(fields []string, rows []interface{}, onDuplicate
 		placeholder = append(placeholder, "("+strings.Join(pl, ",")+")")
 	}
 	SQL += strings.Join(placeholder, ",")
-	// SQL = SQL[0 : len(SQL)-1]
 	if onDuplicate != nil {
 		SQL += " ON CONFLICT " + onDuplicate.(string)
 	}
-	stmt, err := pgm.Conn.Prepare(SQL)
-	if err != nil {
-		fmt.Println("stmt: ", SQL)
-		return err
-	}
-	defer stmt.Close()
-	_, execErr := stmt.Exec(values...)
-	if execErr != nil {
-		fmt.Println("Exec: ", execErr)
-		return execErr
-	}
-	return nil
-}
-
-func (pgm *Mapper) Listen() error {
-	if err := pgm.checkConnection(); err != nil {
-		return err
-	}
-	pgm.Log(LOG, "Listen "+pgm.DBConfig.Host+"/"+pgm.DBConfig.Database+" connecting")
-	reportProblem := func(ev pq.ListenerEventType, err error) {
-		if err != nil {
-			pgm.Log("Error", "pg_listener_create_error", err, nil)
-		}
-	}
-
-	pgm.Listener = pq.NewListener(pgm.ConnectionInfo, 10*time.Second, time.Minute, reportProblem)
-	err := pgm.Listener.Listen("finery")
-	if err != nil {
-		panic(err)
-	}
-	for {
-		pgm.HandleListen()
-	}
-}
-
-func (mapper *Mapper) HandleListen() {
-	l := mapper.Listener
-	for {
-		select {
-		case n := <-l.Notify:
-
-			var data interface{}
-			if n == nil {
-				mapper.Log(ERROR, "Listener extra is nil: ", n.Extra)
-				return
-			}
-			err := json.Unmarshal([]byte(n.Extra), &data)
-			if err != nil {
-				mapper.Log(ERROR, "Error processing JSON: ", err, nil)
-				return
-			}
-			mapper.Handler(data)
-			return
-		case <-time.After(mapper.ListenIdleTimeout):
-			timeout := mapper.ListenIdleTimeout.String()
-			mapper.Log(LOG, mapper.GetDBInfo()+": Received no events for "+timeout+", checking connection")
-			go func() {
-				l.Ping()
-			}()
-			return
-		}
-	}
-}
-
-func (mapper *Mapper) SetHandler(handler func(interface{})) {
-	mapper.Handler = handler
+	return SQL, values
 }
 
 func (m *Mapper) GetDBInfo() string {
@@ -280,14 +402,13 @@ func (m *Mapper) GetDBInfo() string {
 }
 
 func (mapper *Mapper) Close() error {
+	if mapper.tx != nil {
+		return nil
+	}
+	mapper.StopListen()
 	if mapper.Conn != nil {
-		mapper.Log("log", mapper.GetDBInfo()+" closing connection")
+		mapper.log(context.Background(), LevelInfo, "closing connection")
 		return mapper.Conn.Close()
 	}
 	return nil
 }
-
-func (mapper *Mapper) Log(data ...interface{}) error {
-	fmt.Println(data)
-	return errors.New(data[0].(string))
-}
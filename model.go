@@ -0,0 +1,180 @@
+package pg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+/*
+fieldInfo - struct field mapped to a column with `db:"column,options"` tag.
+Options: pk, conflict (conflict target of SaveStruct), readonly (never written), omitempty (not written when zero)
+*/
+type fieldInfo struct {
+	column    string
+	index     []int
+	depth     int
+	pk        bool
+	conflict  bool
+	readonly  bool
+	omitempty bool
+}
+
+/*
+quoted - quoted column name. Tag names are written as in SQL like other names of the package:
+db:"userId" is column userid, db:"\"userId\"" keeps the case
+*/
+func (f *fieldInfo) quoted() string {
+	return pq.QuoteIdentifier(f.column)
+}
+
+/*
+modelInfo - columns of a struct in declaration order. Fields of embedded structs are included,
+outer fields win over embedded ones with the same column
+*/
+type modelInfo struct {
+	fields   []*fieldInfo
+	byColumn map[string]*fieldInfo
+}
+
+var models sync.Map
+
+func getModel(t reflect.Type) (*modelInfo, error) {
+	if m, ok := models.Load(t); ok {
+		return m.(*modelInfo), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("pg: %s is not a struct", t)
+	}
+	m := &modelInfo{byColumn: map[string]*fieldInfo{}}
+	m.collect(t, nil)
+	models.Store(t, m)
+	return m, nil
+}
+
+func (m *modelInfo) collect(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		name, options := parseTag(tag)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// pointers to unexported embedded structs can not be allocated
+			if f.PkgPath != "" && f.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			m.collect(ft, fieldIndex)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		name = resolveName(name)
+		field := &fieldInfo{
+			column:    name,
+			index:     fieldIndex,
+			depth:     len(index),
+			pk:        options["pk"],
+			conflict:  options["conflict"],
+			readonly:  options["readonly"],
+			omitempty: options["omitempty"],
+		}
+		if existing, ok := m.byColumn[name]; ok {
+			if existing.depth <= field.depth {
+				continue
+			}
+			*existing = *field
+			continue
+		}
+		m.fields = append(m.fields, field)
+		m.byColumn[name] = field
+	}
+}
+
+/*
+columns - quoted names of all columns
+*/
+func (m *modelInfo) columns() []string {
+	columns := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		columns[i] = f.quoted()
+	}
+	return columns
+}
+
+/*
+conflictFields - fields tagged with conflict option or, if there are none, pk fields
+*/
+func (m *modelInfo) conflictFields() []*fieldInfo {
+	var conflict, pk []*fieldInfo
+	for _, f := range m.fields {
+		if f.conflict {
+			conflict = append(conflict, f)
+		}
+		if f.pk {
+			pk = append(pk, f)
+		}
+	}
+	if len(conflict) > 0 {
+		return conflict
+	}
+	return pk
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	options := map[string]bool{}
+	for _, option := range parts[1:] {
+		options[strings.TrimSpace(option)] = true
+	}
+	return strings.TrimSpace(parts[0]), options
+}
+
+/*
+snakeCase - converts field name to column name: CreatedAt -> created_at, UserID -> user_id
+*/
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+/*
+fieldByIndex - returns field of struct v, allocating nil embedded pointers on the way
+*/
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
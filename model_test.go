@@ -0,0 +1,114 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ID", "id"},
+		{"Name", "name"},
+		{"CreatedAt", "created_at"},
+		{"UserID", "user_id"},
+		{"HTTPServer", "http_server"},
+		{"userName", "user_name"},
+		{"Address2", "address2"},
+	}
+	for _, tt := range tests {
+		if got := snakeCase(tt.name); got != tt.want {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Timestamps is exported: pointers to unexported embedded structs are skipped
+type Timestamps struct {
+	CreatedAt string `db:"created_at,readonly"`
+	UpdatedAt string
+}
+
+type testBase struct {
+	ID   int64 `db:"id,pk"`
+	Name string
+}
+
+type testAudit struct {
+	By string
+}
+
+type testModel struct {
+	testBase
+	*Timestamps
+	*testAudit
+	Name     string `db:"title"`
+	Email    string `db:"email,conflict,omitempty"`
+	Ignored  string `db:"-"`
+	internal string
+}
+
+func TestCollect(t *testing.T) {
+	model, err := getModel(reflect.TypeOf(testModel{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fieldInfo{
+		{column: "id", index: []int{0, 0}, depth: 1, pk: true},
+		{column: "name", index: []int{0, 1}, depth: 1},
+		{column: "created_at", index: []int{1, 0}, depth: 1, readonly: true},
+		{column: "updated_at", index: []int{1, 1}, depth: 1},
+		{column: "title", index: []int{3}},
+		{column: "email", index: []int{4}, conflict: true, omitempty: true},
+	}
+	if len(model.fields) != len(want) {
+		t.Fatalf("got %d fields, want %d: %v", len(model.fields), len(want), model.columns())
+	}
+	for i, f := range model.fields {
+		if !reflect.DeepEqual(*f, want[i]) {
+			t.Errorf("field %d = %+v, want %+v", i, *f, want[i])
+		}
+	}
+	if keys := model.conflictFields(); len(keys) != 1 || keys[0].column != "email" {
+		t.Errorf("conflictFields = %v", keys)
+	}
+}
+
+func TestCollectOuterFieldWins(t *testing.T) {
+	type outer struct {
+		testBase
+		ID string `db:"id"`
+	}
+	model, err := getModel(reflect.TypeOf(outer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := model.byColumn["id"]
+	if f == nil || !reflect.DeepEqual(f.index, []int{1}) || f.pk {
+		t.Errorf("id field = %+v, want outer field", f)
+	}
+}
+
+func TestCollectTagNames(t *testing.T) {
+	type names struct {
+		UserID int64 `db:"userId"`
+		Tenant int64 `db:"\"Tenant\""`
+	}
+	model, err := getModel(reflect.TypeOf(names{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := model.columns(); !reflect.DeepEqual(got, []string{`"userid"`, `"Tenant"`}) {
+		t.Errorf("columns = %v, want unquoted name folded and quoted name kept", got)
+	}
+}
+
+func TestFieldByIndexAllocatesEmbedded(t *testing.T) {
+	var m testModel
+	fieldByIndex(reflect.ValueOf(&m).Elem(), []int{1, 1}).SetString("now")
+	if m.Timestamps == nil || m.UpdatedAt != "now" {
+		t.Errorf("embedded pointer is not allocated: %+v", m.Timestamps)
+	}
+}
@@ -0,0 +1,82 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+)
+
+/*
+MaxNotifyPayload - Postgres NOTIFY payload must be shorter than this number of bytes
+*/
+const MaxNotifyPayload = 8000
+
+/*
+PayloadTooLargeError - returned by Notify when encoded payload does not fit into NOTIFY
+*/
+type PayloadTooLargeError struct {
+	Channel string
+	Size    int
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return "pg: notification payload for " + e.Channel + " is " + strconv.Itoa(e.Size) +
+		" bytes, must be less than " + strconv.Itoa(MaxNotifyPayload)
+}
+
+/*
+Notify - sends JSON encoded payload to channel with pg_notify
+*/
+func (pgm *Mapper) Notify(channel string, payload interface{}) error {
+	return pgm.NotifyContext(context.Background(), channel, payload)
+}
+
+/*
+NotifyContext - Notify with context
+*/
+func (pgm *Mapper) NotifyContext(ctx context.Context, channel string, payload interface{}) error {
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	return pgm.notify(ctx, pgm.db(), channel, payload)
+}
+
+/*
+NotifyTx - sends notification inside transaction. Listeners receive it only after commit
+*/
+func (pgm *Mapper) NotifyTx(tx *sql.Tx, channel string, payload interface{}) error {
+	return pgm.NotifyTxContext(context.Background(), tx, channel, payload)
+}
+
+/*
+NotifyTxContext - NotifyTx with context
+*/
+func (pgm *Mapper) NotifyTxContext(ctx context.Context, tx *sql.Tx, channel string, payload interface{}) error {
+	return pgm.notify(ctx, tx, channel, payload)
+}
+
+func (pgm *Mapper) notify(ctx context.Context, db executor, channel string, payload interface{}) error {
+	data, err := encodeNotifyPayload(channel, payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := pgm.withTimeout(ctx)
+	defer cancel()
+	SQL := "SELECT pg_notify($1, $2)"
+	args := []interface{}{channel, data}
+	ctx, event := pgm.beginQuery(ctx, "", SQL, args)
+	result, err := db.ExecContext(ctx, SQL, args...)
+	return pgm.endQuery(ctx, event, affectedRows(result), err)
+}
+
+func encodeNotifyPayload(channel string, payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	if len(data) >= MaxNotifyPayload {
+		return "", &PayloadTooLargeError{Channel: channel, Size: len(data)}
+	}
+	return string(data), nil
+}
@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNotifyPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+		size    int // size of too large payload, zero if it fits
+	}{
+		{"below limit", strings.Repeat("a", MaxNotifyPayload-3), 0},
+		{"at limit", strings.Repeat("a", MaxNotifyPayload-2), MaxNotifyPayload},
+		{"multibyte counted in bytes", strings.Repeat("é", MaxNotifyPayload/2), MaxNotifyPayload + 2},
+		{"object", map[string]string{"id": "1"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeNotifyPayload("events", tt.payload)
+			if tt.size == 0 {
+				if err != nil {
+					t.Fatalf("encodeNotifyPayload() error = %v", err)
+				}
+				if len(data) >= MaxNotifyPayload {
+					t.Errorf("encoded %d bytes, want less than %d", len(data), MaxNotifyPayload)
+				}
+				return
+			}
+			var tooLarge *PayloadTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("encodeNotifyPayload() error = %v, want *PayloadTooLargeError", err)
+			}
+			if tooLarge.Size != tt.size || tooLarge.Channel != "events" {
+				t.Errorf("error = %+v, want size %d of events", tooLarge, tt.size)
+			}
+		})
+	}
+}
+
+func TestNotifyTooLarge(t *testing.T) {
+	pgm, driver := fakeMapper(t, "events")
+	err := pgm.Notify("events", strings.Repeat("a", MaxNotifyPayload))
+	var tooLarge *PayloadTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Notify() error = %v, want *PayloadTooLargeError", err)
+	}
+	if len(driver.statements) != 0 {
+		t.Errorf("statements = %v, want payload rejected before pg_notify", driver.statements)
+	}
+}
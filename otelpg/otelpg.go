@@ -0,0 +1,182 @@
+/*
+Package otelpg - OpenTelemetry instrumentation of pg.Mapper: a span per statement, a span per listener
+connection event, a span per handled notification and query/notification metrics
+*/
+package otelpg
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	pg "github.com/niklucky/go-pg"
+)
+
+const instrumentationName = "github.com/niklucky/go-pg/otelpg"
+
+/*
+Config - providers used by instrumentation, global ones are used if not set
+*/
+type Config struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+	// DisableStatement - do not put SQL into db.statement attribute
+	DisableStatement bool
+}
+
+type instrumentation struct {
+	config Config
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+
+	queryDuration  metric.Float64Histogram
+	queryErrors    metric.Int64Counter
+	notifications  metric.Int64Counter
+	notifyDuration metric.Float64Histogram
+	listenerEvents metric.Int64Counter
+}
+
+/*
+Instrument - adds query and listener hooks creating spans and metrics to mapper.
+Should be called before mapper is used
+*/
+func Instrument(mapper *pg.Mapper, config Config) error {
+	_, err := instrument(mapper, config)
+	return err
+}
+
+/*
+instrument - Instrument returning added hooks
+*/
+func instrument(mapper *pg.Mapper, config Config) (*instrumentation, error) {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.MeterProvider == nil {
+		config.MeterProvider = otel.GetMeterProvider()
+	}
+	meter := config.MeterProvider.Meter(instrumentationName)
+	i := &instrumentation{
+		config: config,
+		tracer: config.TracerProvider.Tracer(instrumentationName),
+		attrs: []attribute.KeyValue{
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.name", mapper.DBConfig.Database),
+			attribute.String("server.address", mapper.DBConfig.Host),
+		},
+	}
+	var err error
+	if i.queryDuration, err = meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database statements"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if i.queryErrors, err = meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Number of failed database statements")); err != nil {
+		return nil, err
+	}
+	if i.notifications, err = meter.Int64Counter("pg.notifications.handled",
+		metric.WithDescription("Number of handled notifications")); err != nil {
+		return nil, err
+	}
+	if i.notifyDuration, err = meter.Float64Histogram("pg.notifications.duration",
+		metric.WithDescription("Duration of notification handling"), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if i.listenerEvents, err = meter.Int64Counter("pg.listener.events",
+		metric.WithDescription("Number of listener connection events")); err != nil {
+		return nil, err
+	}
+	mapper.AddQueryHook(i)
+	mapper.AddListenerHook(i)
+	return i, nil
+}
+
+func (i *instrumentation) BeforeQuery(ctx context.Context, event *pg.QueryEvent) context.Context {
+	operation := operationName(event.SQL)
+	attrs := append([]attribute.KeyValue{
+		attribute.String("db.operation", operation),
+		attribute.String("db.sql.table", event.Source),
+	}, i.attrs...)
+	if !i.config.DisableStatement {
+		attrs = append(attrs, attribute.String("db.statement", event.SQL))
+	}
+	name := operation
+	if event.Source != "" {
+		name += " " + event.Source
+	}
+	ctx, _ = i.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func (i *instrumentation) AfterQuery(ctx context.Context, event *pg.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if event.RowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", event.RowsAffected))
+	}
+	attrs := metric.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operationName(event.SQL)),
+		attribute.String("db.sql.table", event.Source),
+	)
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+		i.queryErrors.Add(ctx, 1, attrs)
+	}
+	i.queryDuration.Record(ctx, event.Duration.Seconds(), attrs)
+	span.End()
+}
+
+/*
+OnListenerEvent - records listener event on its own short span as listen context usually has no span
+*/
+func (i *instrumentation) OnListenerEvent(ctx context.Context, event pg.ListenerEvent) {
+	kind := attribute.String("pg.listener.event", event.Type.String())
+	_, span := i.tracer.Start(ctx, "listener "+event.Type.String(),
+		trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(append([]attribute.KeyValue{kind}, i.attrs...)...))
+	span.AddEvent("pg.listener." + event.Type.String())
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End()
+	i.listenerEvents.Add(ctx, 1, metric.WithAttributes(kind))
+}
+
+func (i *instrumentation) BeforeNotification(ctx context.Context, event *pg.NotificationEvent) context.Context {
+	attrs := append([]attribute.KeyValue{attribute.String("pg.channel", event.Channel)}, i.attrs...)
+	ctx, _ = i.tracer.Start(ctx, "notification "+event.Channel,
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func (i *instrumentation) AfterNotification(ctx context.Context, event *pg.NotificationEvent) {
+	span := trace.SpanFromContext(ctx)
+	attrs := metric.WithAttributes(
+		attribute.String("pg.channel", event.Channel),
+		attribute.Bool("error", event.Err != nil),
+	)
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	i.notifications.Add(ctx, 1, attrs)
+	i.notifyDuration.Record(ctx, event.Duration.Seconds(), attrs)
+	span.End()
+}
+
+/*
+operationName - first keyword of the statement: SELECT, INSERT etc.
+*/
+func operationName(SQL string) string {
+	fields := strings.Fields(SQL)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
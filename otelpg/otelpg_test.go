@@ -0,0 +1,207 @@
+package otelpg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	pg "github.com/niklucky/go-pg"
+)
+
+type testProviders struct {
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+	tp     *sdktrace.TracerProvider
+}
+
+func setup(t *testing.T, config Config) (*instrumentation, *testProviders) {
+	p := &testProviders{
+		spans:  tracetest.NewInMemoryExporter(),
+		reader: sdkmetric.NewManualReader(),
+	}
+	p.tp = sdktrace.NewTracerProvider(sdktrace.WithSyncer(p.spans))
+	config.TracerProvider = p.tp
+	config.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(p.reader))
+	mapper := &pg.Mapper{DBConfig: pg.DBConfig{Host: "db.local", Database: "app"}}
+	i, err := instrument(mapper, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i, p
+}
+
+func (p *testProviders) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	if err := p.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+func attrValue(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func runQuery(i *instrumentation, event *pg.QueryEvent) {
+	ctx := i.BeforeQuery(context.Background(), event)
+	event.Duration = 5 * time.Millisecond
+	i.AfterQuery(ctx, event)
+}
+
+func TestQuerySpansAndMetrics(t *testing.T) {
+	i, p := setup(t, Config{})
+	runQuery(i, &pg.QueryEvent{SQL: "insert into users (id) values ($1)", Source: "users", ArgCount: 1, RowsAffected: 1})
+	runQuery(i, &pg.QueryEvent{SQL: "SELECT * FROM users", Source: "users", RowsAffected: -1, Err: errors.New("boom")})
+
+	spans := p.spans.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	insert, sel := spans[0], spans[1]
+	if insert.Name != "INSERT users" || insert.SpanKind != trace.SpanKindClient {
+		t.Errorf("span %q kind %v", insert.Name, insert.SpanKind)
+	}
+	for key, want := range map[string]string{
+		"db.system":      "postgresql",
+		"db.name":        "app",
+		"server.address": "db.local",
+		"db.operation":   "INSERT",
+		"db.sql.table":   "users",
+		"db.statement":   "insert into users (id) values ($1)",
+	} {
+		if v, ok := attrValue(insert.Attributes, key); !ok || v.AsString() != want {
+			t.Errorf("attribute %s = %v, want %q", key, v.Emit(), want)
+		}
+	}
+	if v, ok := attrValue(insert.Attributes, "db.rows_affected"); !ok || v.AsInt64() != 1 {
+		t.Errorf("db.rows_affected = %v", v.Emit())
+	}
+	if _, ok := attrValue(sel.Attributes, "db.rows_affected"); ok {
+		t.Error("db.rows_affected is set for query returning rows")
+	}
+	if sel.Status.Code != codes.Error || sel.Status.Description != "boom" {
+		t.Errorf("status = %+v, want error", sel.Status)
+	}
+
+	metrics := p.metrics(t)
+	duration, ok := metrics["db.client.operation.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("db.client.operation.duration = %T", metrics["db.client.operation.duration"])
+	}
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+	if count != 2 {
+		t.Errorf("duration count = %d, want 2", count)
+	}
+	failures, ok := metrics["db.client.operation.errors"].(metricdata.Sum[int64])
+	if !ok || len(failures.DataPoints) != 1 || failures.DataPoints[0].Value != 1 {
+		t.Errorf("db.client.operation.errors = %+v", metrics["db.client.operation.errors"])
+	}
+}
+
+func TestDisableStatement(t *testing.T) {
+	i, p := setup(t, Config{DisableStatement: true})
+	runQuery(i, &pg.QueryEvent{SQL: "SELECT 1", RowsAffected: -1})
+	spans := p.spans.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "SELECT" {
+		t.Fatalf("spans = %v", spans)
+	}
+	if _, ok := attrValue(spans[0].Attributes, "db.statement"); ok {
+		t.Error("db.statement is set with DisableStatement")
+	}
+}
+
+func TestNotificationSpansAndMetrics(t *testing.T) {
+	i, p := setup(t, Config{})
+	for _, err := range []error{nil, errors.New("bad payload")} {
+		event := &pg.NotificationEvent{Channel: "orders", Start: time.Now()}
+		ctx := i.BeforeNotification(context.Background(), event)
+		event.Duration = time.Millisecond
+		event.Err = err
+		i.AfterNotification(ctx, event)
+	}
+
+	spans := p.spans.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Name != "notification orders" || spans[0].SpanKind != trace.SpanKindConsumer {
+		t.Errorf("span %q kind %v", spans[0].Name, spans[0].SpanKind)
+	}
+	if v, _ := attrValue(spans[0].Attributes, "pg.channel"); v.AsString() != "orders" {
+		t.Errorf("pg.channel = %v", v.Emit())
+	}
+	if spans[0].Status.Code == codes.Error || spans[1].Status.Code != codes.Error {
+		t.Errorf("statuses = %v, %v", spans[0].Status, spans[1].Status)
+	}
+
+	handled, ok := p.metrics(t)["pg.notifications.handled"].(metricdata.Sum[int64])
+	if !ok || len(handled.DataPoints) != 2 {
+		t.Fatalf("pg.notifications.handled = %+v, want points for success and error", handled)
+	}
+}
+
+func TestListenerEvents(t *testing.T) {
+	i, p := setup(t, Config{})
+	i.OnListenerEvent(context.Background(), pg.ListenerEvent{Type: pg.ListenerEventDisconnected, Err: errors.New("reset")})
+	i.OnListenerEvent(context.Background(), pg.ListenerEvent{Type: pg.ListenerEventReconnected})
+
+	spans := p.spans.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want a span per event", len(spans))
+	}
+	disconnected, reconnected := spans[0], spans[1]
+	if disconnected.Name != "listener "+pg.ListenerEventDisconnected.String() {
+		t.Errorf("span name = %q", disconnected.Name)
+	}
+	if v, _ := attrValue(disconnected.Attributes, "pg.listener.event"); v.AsString() != pg.ListenerEventDisconnected.String() {
+		t.Errorf("pg.listener.event = %v", v.Emit())
+	}
+	if disconnected.Status.Code != codes.Error || disconnected.Status.Description != "reset" {
+		t.Errorf("status = %+v, want error", disconnected.Status)
+	}
+	if reconnected.Status.Code == codes.Error || len(reconnected.Events) != 1 {
+		t.Errorf("reconnected span = %+v", reconnected)
+	}
+
+	events, ok := p.metrics(t)["pg.listener.events"].(metricdata.Sum[int64])
+	if !ok || len(events.DataPoints) != 2 {
+		t.Errorf("pg.listener.events = %+v", events)
+	}
+}
+
+func TestOperationName(t *testing.T) {
+	tests := map[string]string{
+		"select 1":                             "SELECT",
+		"  INSERT INTO t VALUES 1":             "INSERT",
+		"WITH x AS (SELECT 1) SELECT * FROM x": "WITH",
+		"":                                     "",
+	}
+	for SQL, want := range tests {
+		if got := operationName(SQL); got != want {
+			t.Errorf("operationName(%q) = %q, want %q", SQL, got, want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+/*
+OutboxConfig - event table used to replay notifications missed while listener was disconnected.
+Every notification payload must carry event id (IDField) which is the id of the row in the table
+*/
+type OutboxConfig struct {
+	Table         string
+	IDColumn      string // default "id"
+	ChannelColumn string // default "channel"
+	PayloadColumn string // default "payload"
+	IDField       string // payload field with event id, default "id"
+	BatchSize     int    // rows fetched per replay query, default 500
+	// Window - number of dispatched ids remembered to catch events committed out of id order, default 1000.
+	// Replay re-reads this range, so an event is lost only if it commits after Window newer events
+	Window int
+}
+
+func (o *OutboxConfig) column(name, def string) string {
+	if name == "" {
+		name = def
+	}
+	return quoteIdentifier(name)
+}
+
+func (o *OutboxConfig) replayQuery() string {
+	id := o.column(o.IDColumn, "id")
+	return "SELECT " + id + ", " + o.column(o.ChannelColumn, "channel") + ", " + o.column(o.PayloadColumn, "payload") +
+		" FROM " + quoteIdentifier(o.Table) +
+		" WHERE " + id + " > $1 AND " + o.column(o.ChannelColumn, "channel") + " = ANY($2)" +
+		" ORDER BY " + id + " LIMIT " + strconv.Itoa(o.batchSize())
+}
+
+func (o *OutboxConfig) window() int {
+	if o.Window > 0 {
+		return o.Window
+	}
+	return 1000
+}
+
+func (o *OutboxConfig) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return 500
+}
+
+/*
+eventID - extracts event id from notification payload
+*/
+func (o *OutboxConfig) eventID(payload []byte) (int64, bool) {
+	field := o.IDField
+	if field == "" {
+		field = "id"
+	}
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(payload, &probe) != nil {
+		return 0, false
+	}
+	raw, ok := probe[field]
+	if !ok {
+		return 0, false
+	}
+	var id int64
+	if json.Unmarshal(raw, &id) != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+/*
+eventLog - ids of dispatched Outbox events. Ids are assigned on insert but become visible on commit, so
+events may arrive out of id order: any id not dispatched yet is accepted, not only ids above the last one
+*/
+type eventLog struct {
+	mu    sync.Mutex
+	last  int64          // every dispatched event up to last is processed
+	floor int64          // ids up to floor are forgotten and treated as processed
+	ids   map[int64]bool // dispatched ids above floor, true when processed
+}
+
+/*
+reset - forgets dispatched ids and treats everything up to id as processed
+*/
+func (l *eventLog) reset(id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last, l.floor, l.ids = id, id, nil
+}
+
+func (l *eventLog) lastID() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last
+}
+
+/*
+replayFrom - id replay starts after. Ids between it and last that were never dispatched are gaps
+left by transactions not committed yet, replay picks them up
+*/
+func (l *eventLog) replayFrom() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.floor
+}
+
+/*
+dispatch - registers event. Returns false if it was already dispatched
+*/
+func (l *eventLog) dispatch(id int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if id <= l.floor {
+		return false
+	}
+	if _, ok := l.ids[id]; ok {
+		return false
+	}
+	if l.ids == nil {
+		l.ids = make(map[int64]bool)
+	}
+	l.ids[id] = false
+	return true
+}
+
+/*
+forget - removes event which was dispatched but not handled (e.g. dropped), so replay delivers it again
+*/
+func (l *eventLog) forget(id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if done, ok := l.ids[id]; ok && !done {
+		delete(l.ids, id)
+	}
+}
+
+/*
+done - marks event as processed, moves last to the highest id below every pending one and
+forgets the oldest processed ids exceeding window
+*/
+func (l *eventLog) done(id int64, window int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.ids[id]; !ok {
+		return
+	}
+	l.ids[id] = true
+	ids := make([]int64, 0, len(l.ids))
+	for dispatched := range l.ids {
+		ids = append(ids, dispatched)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, dispatched := range ids {
+		if !l.ids[dispatched] {
+			break
+		}
+		if dispatched > l.last {
+			l.last = dispatched
+		}
+	}
+	excess := len(ids) - window
+	for _, dispatched := range ids {
+		if excess <= 0 || dispatched > l.last {
+			break
+		}
+		delete(l.ids, dispatched)
+		l.floor = dispatched
+		excess--
+	}
+}
+
+/*
+LastEventID - returns id of the last processed event in Outbox mode. All dispatched events up to it
+are handled, so it is safe to persist it and restore with SetLastEventID
+*/
+func (pgm *Mapper) LastEventID() int64 {
+	return pgm.events.lastID()
+}
+
+/*
+SetLastEventID - sets id of the last processed event, e.g. restored from persistent storage before Listen.
+Listen replays events after this id on start. If it is not set Listen starts from the newest event in the table
+*/
+func (pgm *Mapper) SetLastEventID(id int64) {
+	pgm.events.reset(id)
+}
+
+/*
+acceptNotification - checks live notification against dispatched events and sets its event id.
+Notifications already delivered by replay are skipped
+*/
+func (pgm *Mapper) acceptNotification(job *notificationJob) bool {
+	if pgm.Outbox == nil {
+		return true
+	}
+	id, ok := pgm.Outbox.eventID(job.payload)
+	if !ok {
+		return true
+	}
+	if !pgm.events.dispatch(id) {
+		return false
+	}
+	job.eventID = id
+	return true
+}
+
+/*
+eventDone - records Outbox event as processed after its handler returned
+*/
+func (pgm *Mapper) eventDone(job notificationJob) {
+	if pgm.Outbox != nil && job.eventID != 0 {
+		pgm.events.done(job.eventID, pgm.Outbox.window())
+	}
+}
+
+/*
+eventDropped - forgets Outbox event which was not handled so it is replayed after reconnect
+*/
+func (pgm *Mapper) eventDropped(job notificationJob) {
+	if pgm.Outbox != nil && job.eventID != 0 {
+		pgm.events.forget(job.eventID)
+	}
+}
+
+/*
+replayOutbox - dispatches events from Outbox table which were not dispatched yet
+*/
+func (pgm *Mapper) replayOutbox(ctx context.Context, pool *workerPool) error {
+	if pgm.Outbox == nil {
+		return nil
+	}
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	SQL := pgm.Outbox.replayQuery()
+	channels := pq.Array(pgm.Channels())
+	after := pgm.events.replayFrom()
+	for {
+		jobs, last, count, err := pgm.loadOutbox(ctx, SQL, after, channels)
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if pool != nil {
+				pool.submit(ctx, job)
+			} else {
+				pgm.processNotification(job)
+			}
+		}
+		if count < pgm.Outbox.batchSize() || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		after = last
+	}
+}
+
+/*
+startOutbox - replays events missed since last event id or, if it is not set, seeks to the newest event
+*/
+func (pgm *Mapper) startOutbox(ctx context.Context, pool *workerPool) error {
+	if pgm.LastEventID() > 0 {
+		return pgm.replayOutbox(ctx, pool)
+	}
+	id := pgm.Outbox.column(pgm.Outbox.IDColumn, "id")
+	SQL := "SELECT COALESCE(MAX(" + id + "), 0) FROM " + quoteIdentifier(pgm.Outbox.Table)
+	var last int64
+	ctx, event := pgm.beginQuery(ctx, pgm.Outbox.Table, SQL, nil)
+	err := pgm.db().QueryRowContext(ctx, SQL).Scan(&last)
+	err = pgm.endQuery(ctx, event, -1, err)
+	if err != nil {
+		return err
+	}
+	pgm.events.reset(last)
+	return nil
+}
+
+/*
+loadOutbox - reads batch of events after id. Returns events not dispatched yet, id of the last row read
+and number of rows read
+*/
+func (pgm *Mapper) loadOutbox(ctx context.Context, SQL string, after int64, channels interface{}) ([]notificationJob, int64, int, error) {
+	args := []interface{}{after, channels}
+	ctx, event := pgm.beginQuery(ctx, pgm.Outbox.Table, SQL, args)
+	rows, err := pgm.db().QueryContext(ctx, SQL, args...)
+	err = pgm.endQuery(ctx, event, -1, err)
+	if err != nil {
+		return nil, after, 0, err
+	}
+	defer rows.Close()
+	var jobs []notificationJob
+	count := 0
+	for rows.Next() {
+		var job notificationJob
+		if err := rows.Scan(&job.eventID, &job.channel, &job.payload); err != nil {
+			return nil, after, count, err
+		}
+		count++
+		after = job.eventID
+		if pgm.events.dispatch(job.eventID) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, after, count, rows.Err()
+}
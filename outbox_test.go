@@ -0,0 +1,96 @@
+package pg
+
+import "testing"
+
+func TestEventLogOutOfOrder(t *testing.T) {
+	var l eventLog
+	l.reset(10)
+	if !l.dispatch(12) {
+		t.Fatal("dispatch(12) = false")
+	}
+	if !l.dispatch(11) {
+		t.Fatal("dispatch(11) committed after 12 = false, want true")
+	}
+	if l.dispatch(12) {
+		t.Fatal("dispatch(12) again = true, want false")
+	}
+
+	l.done(12, 1000)
+	if got := l.lastID(); got != 10 {
+		t.Fatalf("last = %d while 11 is pending, want 10", got)
+	}
+	l.done(11, 1000)
+	if got := l.lastID(); got != 12 {
+		t.Fatalf("last = %d, want 12", got)
+	}
+	if got := l.replayFrom(); got != 10 {
+		t.Fatalf("replayFrom = %d, want 10", got)
+	}
+}
+
+func TestEventLogForget(t *testing.T) {
+	var l eventLog
+	l.reset(0)
+	l.dispatch(1)
+	l.forget(1)
+	if !l.dispatch(1) {
+		t.Fatal("dropped event is not dispatched again")
+	}
+	l.done(1, 1000)
+	l.forget(1)
+	if l.dispatch(1) {
+		t.Fatal("processed event is dispatched again")
+	}
+}
+
+func TestEventLogWindow(t *testing.T) {
+	var l eventLog
+	l.reset(10)
+	for id := int64(11); id <= 15; id++ {
+		l.dispatch(id)
+		l.done(id, 2)
+	}
+	if got := l.replayFrom(); got != 13 {
+		t.Fatalf("replayFrom = %d, want 13", got)
+	}
+	if l.dispatch(12) {
+		t.Fatal("dispatch(12) below floor = true")
+	}
+	if l.dispatch(14) {
+		t.Fatal("dispatch(14) in window = true")
+	}
+	if !l.dispatch(16) {
+		t.Fatal("dispatch(16) = false")
+	}
+}
+
+func TestAcceptNotification(t *testing.T) {
+	pgm := &Mapper{Outbox: &OutboxConfig{Table: "events", IDField: "event_id"}}
+	pgm.SetLastEventID(5)
+	tests := []struct {
+		payload string
+		accept  bool
+		eventID int64
+	}{
+		{`{"event_id":7}`, true, 7},
+		{`{"event_id":6}`, true, 6},
+		{`{"event_id":7}`, false, 0},
+		{`{"event_id":5}`, false, 0},
+		{`{"other":1}`, true, 0},
+		{`not json`, true, 0},
+	}
+	for _, tt := range tests {
+		job := notificationJob{channel: "events", payload: []byte(tt.payload)}
+		if got := pgm.acceptNotification(&job); got != tt.accept || job.eventID != tt.eventID {
+			t.Errorf("acceptNotification(%s) = %v, id %d, want %v, id %d", tt.payload, got, job.eventID, tt.accept, tt.eventID)
+		}
+	}
+	pgm.eventDone(notificationJob{eventID: 7})
+	if got := pgm.LastEventID(); got != 5 {
+		t.Errorf("LastEventID = %d before 6 is processed, want 5", got)
+	}
+	pgm.eventDone(notificationJob{eventID: 6})
+	if got := pgm.LastEventID(); got != 7 {
+		t.Errorf("LastEventID = %d, want 7", got)
+	}
+}
@@ -0,0 +1,165 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+ErrQueueFull - reported through ErrorHandler when notification is dropped because worker queue is full
+*/
+var ErrQueueFull = errors.New("pg: notification queue is full")
+
+/*
+QueueFullPolicy - what to do with notification when worker queue is full
+*/
+type QueueFullPolicy int
+
+const (
+	// QueueFullBlock - wait for a free slot. Listener stops reading notifications until then
+	QueueFullBlock QueueFullPolicy = iota
+	// QueueFullDrop - drop notification and report ErrQueueFull
+	QueueFullDrop
+)
+
+/*
+WorkerPoolConfig - configuration of workers handling notifications. With zero Workers notifications
+are handled synchronously by the listener loop
+*/
+type WorkerPoolConfig struct {
+	Workers int
+	// QueueSize - capacity of every worker queue
+	QueueSize int
+	// KeyFunc - notifications with the same key are handled by the same worker in order they were received.
+	// Notifications with empty key (or without KeyFunc) are distributed between workers round-robin
+	KeyFunc    func(channel string, payload []byte) string
+	FullPolicy QueueFullPolicy
+}
+
+/*
+PoolStats - worker pool metrics
+*/
+type PoolStats struct {
+	Workers       int
+	QueueDepth    int
+	QueueCapacity int
+	InFlight      int64
+	Handled       uint64
+	Dropped       uint64
+}
+
+type notificationJob struct {
+	channel string
+	payload []byte
+	eventID int64 // Outbox event id, zero if not tracked
+}
+
+type workerPool struct {
+	mapper   *Mapper
+	config   WorkerPoolConfig
+	queues   []chan notificationJob
+	wg       sync.WaitGroup
+	next     uint32
+	inFlight int64
+	handled  uint64
+	dropped  uint64
+}
+
+func newWorkerPool(mapper *Mapper, config WorkerPoolConfig) *workerPool {
+	p := &workerPool{
+		mapper: mapper,
+		config: config,
+		queues: make([]chan notificationJob, config.Workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan notificationJob, config.QueueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+func (p *workerPool) work(queue chan notificationJob) {
+	defer p.wg.Done()
+	for job := range queue {
+		atomic.AddInt64(&p.inFlight, 1)
+		p.mapper.processNotification(job)
+		atomic.AddInt64(&p.inFlight, -1)
+		atomic.AddUint64(&p.handled, 1)
+	}
+}
+
+func (p *workerPool) queue(job notificationJob) chan notificationJob {
+	if p.config.KeyFunc != nil {
+		if key := p.config.KeyFunc(job.channel, job.payload); key != "" {
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			return p.queues[h.Sum32()%uint32(len(p.queues))]
+		}
+	}
+	return p.queues[atomic.AddUint32(&p.next, 1)%uint32(len(p.queues))]
+}
+
+/*
+submit - puts notification to worker queue according to FullPolicy
+*/
+func (p *workerPool) submit(ctx context.Context, job notificationJob) {
+	queue := p.queue(job)
+	if p.config.FullPolicy == QueueFullDrop {
+		select {
+		case queue <- job:
+		default:
+			atomic.AddUint64(&p.dropped, 1)
+			p.mapper.eventDropped(job)
+			p.mapper.reportError(&ListenerError{Op: "enqueue", Channel: job.channel, Err: ErrQueueFull})
+		}
+		return
+	}
+	select {
+	case queue <- job:
+	case <-ctx.Done():
+		atomic.AddUint64(&p.dropped, 1)
+		p.mapper.eventDropped(job)
+	}
+}
+
+/*
+stop - waits until queued notifications are handled and stops workers
+*/
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+func (p *workerPool) stats() PoolStats {
+	stats := PoolStats{
+		Workers:  len(p.queues),
+		InFlight: atomic.LoadInt64(&p.inFlight),
+		Handled:  atomic.LoadUint64(&p.handled),
+		Dropped:  atomic.LoadUint64(&p.dropped),
+	}
+	for _, queue := range p.queues {
+		stats.QueueDepth += len(queue)
+		stats.QueueCapacity += cap(queue)
+	}
+	return stats
+}
+
+/*
+PoolStats - returns metrics of notification worker pool. Returns zero stats when mapper is not listening
+or WorkerPool is not configured
+*/
+func (pgm *Mapper) PoolStats() PoolStats {
+	pgm.mu.Lock()
+	pool := pgm.pool
+	pgm.mu.Unlock()
+	if pool == nil {
+		return PoolStats{}
+	}
+	return pool.stats()
+}
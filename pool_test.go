@@ -0,0 +1,144 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type poolMessage struct {
+	Key string `json:"key"`
+	Seq int    `json:"seq"`
+}
+
+func poolJob(key string, seq int) notificationJob {
+	payload, _ := json.Marshal(poolMessage{key, seq})
+	return notificationJob{channel: "events", payload: payload}
+}
+
+func TestWorkerPoolKeyOrder(t *testing.T) {
+	pgm := &Mapper{}
+	var mu sync.Mutex
+	received := map[string][]int{}
+	pgm.Handle("events", poolMessage{}, func(channel string, payload interface{}) error {
+		message := payload.(*poolMessage)
+		mu.Lock()
+		received[message.Key] = append(received[message.Key], message.Seq)
+		mu.Unlock()
+		return nil
+	})
+	pool := newWorkerPool(pgm, WorkerPoolConfig{
+		Workers:   4,
+		QueueSize: 10,
+		KeyFunc: func(channel string, payload []byte) string {
+			var message poolMessage
+			json.Unmarshal(payload, &message)
+			return message.Key
+		},
+	})
+	keys := []string{"a", "b", "c", "d", "e"}
+	for seq := 0; seq < 100; seq++ {
+		for _, key := range keys {
+			pool.submit(context.Background(), poolJob(key, seq))
+		}
+	}
+	pool.stop()
+
+	for _, key := range keys {
+		if len(received[key]) != 100 {
+			t.Fatalf("key %s: handled %d notifications, want 100", key, len(received[key]))
+		}
+		for i, seq := range received[key] {
+			if seq != i {
+				t.Fatalf("key %s: notification %d has seq %d, handled out of order", key, i, seq)
+			}
+		}
+	}
+	if stats := pool.stats(); stats.Handled != 500 || stats.Dropped != 0 || stats.QueueDepth != 0 {
+		t.Errorf("stats = %+v, want 500 handled", stats)
+	}
+}
+
+/*
+blockedPool - pool with one worker busy with the first notification until pool is stopped
+with returned func and the queue of size one filled with the second one
+*/
+func blockedPool(t *testing.T, policy QueueFullPolicy) (*Mapper, *workerPool, func()) {
+	pgm := &Mapper{}
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	pgm.Handle("events", poolMessage{}, func(channel string, payload interface{}) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	pool := newWorkerPool(pgm, WorkerPoolConfig{Workers: 1, QueueSize: 1, FullPolicy: policy})
+	pool.submit(context.Background(), poolJob("", 1))
+	<-started
+	pool.submit(context.Background(), poolJob("", 2))
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(release)
+			pool.stop()
+		})
+	}
+	t.Cleanup(stop)
+	return pgm, pool, stop
+}
+
+func TestWorkerPoolDrop(t *testing.T) {
+	pgm, pool, stop := blockedPool(t, QueueFullDrop)
+	var reported []error
+	pgm.ErrorHandler = func(err error) {
+		reported = append(reported, err)
+	}
+	pool.submit(context.Background(), poolJob("", 3))
+
+	if len(reported) != 1 || !errors.Is(reported[0], ErrQueueFull) {
+		t.Errorf("reported errors = %v, want ErrQueueFull", reported)
+	}
+	want := PoolStats{Workers: 1, QueueDepth: 1, QueueCapacity: 1, InFlight: 1, Dropped: 1}
+	if stats := pool.stats(); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	stop()
+	if stats := pool.stats(); stats.Handled != 2 || stats.Dropped != 1 {
+		t.Errorf("stats after stop = %+v, want 2 handled and 1 dropped", stats)
+	}
+}
+
+func TestWorkerPoolBlockCanceled(t *testing.T) {
+	_, pool, _ := blockedPool(t, QueueFullBlock)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.submit(ctx, poolJob("", 3))
+		close(done)
+	}()
+	cancel()
+	<-done
+	if stats := pool.stats(); stats.Dropped != 1 || stats.QueueDepth != 1 {
+		t.Errorf("stats = %+v, want canceled submit counted as dropped", stats)
+	}
+}
+
+func TestMapperPoolStats(t *testing.T) {
+	pgm := &Mapper{}
+	if stats := pgm.PoolStats(); stats != (PoolStats{}) {
+		t.Errorf("PoolStats without pool = %+v, want zero", stats)
+	}
+	pgm.pool = newWorkerPool(pgm, WorkerPoolConfig{Workers: 3, QueueSize: 5})
+	defer pgm.pool.stop()
+	for seq := 0; seq < 6; seq++ {
+		pgm.pool.submit(context.Background(), poolJob(fmt.Sprint(seq), seq))
+	}
+	stats := pgm.PoolStats()
+	if stats.Workers != 3 || stats.QueueCapacity != 15 {
+		t.Errorf("PoolStats = %+v, want 3 workers with capacity 15", stats)
+	}
+}
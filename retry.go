@@ -0,0 +1,113 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*
+RetryPolicy - how TxRetry repeats transactions failed with retryable errors
+*/
+type RetryPolicy struct {
+	MaxAttempts    int           // default 3
+	InitialBackoff time.Duration // default 50ms
+	MaxBackoff     time.Duration // default 1s
+	Multiplier     float64       // default 2
+	Jitter         bool          // randomize backoff in [backoff/2, backoff)
+	// OnRetry - called before every retry with the number of failed attempt, its error and wait time
+	OnRetry func(attempt int, err error, backoff time.Duration)
+}
+
+/*
+IsRetryable - reports whether err is a serialization failure (40001) or a deadlock (40P01)
+*/
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+/*
+TxRetry - runs fn in transaction like TxOptions and re-runs the whole transaction when it fails with
+retryable error. On tx mapper fn runs in a savepoint without retries as the outer transaction is aborted
+*/
+func (pgm *Mapper) TxRetry(ctx context.Context, policy RetryPolicy, opts *sql.TxOptions, fn func(tx *Mapper) error) error {
+	if pgm.tx != nil {
+		return pgm.TxOptions(ctx, opts, fn)
+	}
+	return retry(ctx, policy.withDefaults(), func() error {
+		return pgm.TxOptions(ctx, opts, fn)
+	})
+}
+
+/*
+retry - runs attempt until it succeeds, fails with not retryable error, policy.MaxAttempts are made
+or ctx is done while waiting. Returns error of the last attempt
+*/
+func retry(ctx context.Context, policy RetryPolicy, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || !IsRetryable(err) || n >= policy.MaxAttempts {
+			return err
+		}
+		wait := policy.backoff(n)
+		if policy.Jitter {
+			wait = jitter(wait, rand.Int63n)
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(n, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+/*
+backoff - wait time after failed attempt: InitialBackoff multiplied by Multiplier for every previous
+retry and limited by MaxBackoff
+*/
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+/*
+jitter - random wait in [wait/2, wait), random is rand.Int63n
+*/
+func jitter(wait time.Duration, random func(n int64) int64) time.Duration {
+	return wait/2 + time.Duration(random(int64(wait-wait/2)))
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 50 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	return p
+}
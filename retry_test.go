@@ -0,0 +1,152 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   RetryPolicy
+	}{
+		{
+			name:   "zero",
+			policy: RetryPolicy{},
+			want:   RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2},
+		},
+		{
+			name:   "invalid",
+			policy: RetryPolicy{MaxAttempts: -1, InitialBackoff: -1, MaxBackoff: -1, Multiplier: 0.5, Jitter: true},
+			want:   RetryPolicy{MaxAttempts: 3, InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: true},
+		},
+		{
+			name:   "set",
+			policy: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute, Multiplier: 1.5},
+			want:   RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute, Multiplier: 1.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.withDefaults(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 2}
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+	policy.InitialBackoff = time.Minute
+	if got := policy.backoff(1); got != policy.MaxBackoff {
+		t.Errorf("backoff(1) = %v, want MaxBackoff", got)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	wait := 100 * time.Millisecond
+	low := jitter(wait, func(n int64) int64 { return 0 })
+	high := jitter(wait, func(n int64) int64 { return n - 1 })
+	if low != wait/2 || high != wait-1 {
+		t.Errorf("jitter range = [%v, %v], want [%v, %v]", low, high, wait/2, wait-1)
+	}
+	if got := jitter(time.Nanosecond, func(n int64) int64 { return n - 1 }); got != 0 {
+		t.Errorf("jitter(1ns) = %v, want 0", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	retryable := &pq.Error{Code: "40001"}
+	other := errors.New("other")
+	tests := []struct {
+		name     string
+		errors   []error // results of attempts, nil after the end
+		attempts int
+		err      error
+	}{
+		{"success", nil, 1, nil},
+		{"retried until success", []error{retryable, retryable}, 3, nil},
+		{"not retryable", []error{retryable, other}, 2, other},
+		{"attempts exhausted", []error{retryable, retryable, retryable, retryable}, 3, retryable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var retries []int
+			policy := RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Microsecond,
+				MaxBackoff:     time.Millisecond,
+				Multiplier:     2,
+				OnRetry: func(attempt int, err error, backoff time.Duration) {
+					if err != retryable || backoff <= 0 {
+						t.Errorf("OnRetry(%d, %v, %v)", attempt, err, backoff)
+					}
+					retries = append(retries, attempt)
+				},
+			}
+			attempts := 0
+			err := retry(context.Background(), policy, func() error {
+				attempts++
+				if attempts <= len(tt.errors) {
+					return tt.errors[attempts-1]
+				}
+				return nil
+			})
+			if err != tt.err || attempts != tt.attempts {
+				t.Errorf("retry() = %v after %d attempts, want %v after %d", err, attempts, tt.err, tt.attempts)
+			}
+			if len(retries) != tt.attempts-1 {
+				t.Errorf("OnRetry called for attempts %v, want %d calls", retries, tt.attempts-1)
+			}
+			for i, attempt := range retries {
+				if attempt != i+1 {
+					t.Errorf("OnRetry attempts = %v, want 1, 2, ...", retries)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRetryContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	retryable := &pq.Error{Code: "40P01"}
+	attempts := 0
+	err := retry(ctx, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour, Multiplier: 2}, func() error {
+		attempts++
+		return retryable
+	})
+	if err != retryable || attempts != 1 {
+		t.Errorf("retry() = %v after %d attempts, want last error after 1", err, attempts)
+	}
+}
+
+func TestTxRetry(t *testing.T) {
+	pgm, driver := fakeMapper(t, "users")
+	attempts := 0
+	policy := RetryPolicy{InitialBackoff: time.Microsecond}
+	err := pgm.TxRetry(context.Background(), policy, nil, func(tx *Mapper) error {
+		attempts++
+		if attempts == 1 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	want := []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}
+	if err != nil || attempts != 2 || !reflect.DeepEqual(driver.statements, want) {
+		t.Errorf("TxRetry() = %v after %d attempts, statements %q, want %q", err, attempts, driver.statements, want)
+	}
+}
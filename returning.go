@@ -0,0 +1,164 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+/*
+CreateReturning - inserts row and scans returning columns of the new row into dest
+*/
+func (pgm *Mapper) CreateReturning(fields []string, values []interface{}, returning []string, dest ...interface{}) error {
+	return pgm.CreateReturningContext(context.Background(), fields, values, returning, dest...)
+}
+
+/*
+CreateReturningContext - CreateReturning with context
+*/
+func (pgm *Mapper) CreateReturningContext(ctx context.Context, fields []string, values []interface{}, returning []string, dest ...interface{}) error {
+	SQL := pgm.generateInsertQuery(fields) + returningClause(returning)
+	return pgm.queryRow(ctx, SQL, values, dest)
+}
+
+/*
+SaveReturning - same as Save but scans returning columns into dest and reports whether row was
+inserted (true) or updated (false). Returns ErrNotFound if conflicting row was skipped
+*/
+func (pgm *Mapper) SaveReturning(fields []string, values []interface{}, key map[string]interface{}, returning []string, dest ...interface{}) (bool, error) {
+	return pgm.SaveReturningContext(context.Background(), fields, values, key, returning, dest...)
+}
+
+/*
+SaveReturningContext - SaveReturning with context
+*/
+func (pgm *Mapper) SaveReturningContext(ctx context.Context, fields []string, values []interface{}, key map[string]interface{}, returning []string, dest ...interface{}) (bool, error) {
+	var columns []string
+	for column := range key {
+		columns = append(columns, column)
+	}
+	return pgm.UpsertReturningContext(ctx, fields, values, UpsertOptions{ConflictColumns: sortedStrings(columns)}, returning, dest...)
+}
+
+/*
+UpsertReturning - same as Upsert but scans returning columns into dest and reports whether row was
+inserted (true) or updated (false). Returns ErrNotFound if conflicting row was skipped
+*/
+func (pgm *Mapper) UpsertReturning(fields []string, values []interface{}, options UpsertOptions, returning []string, dest ...interface{}) (bool, error) {
+	return pgm.UpsertReturningContext(context.Background(), fields, values, options, returning, dest...)
+}
+
+/*
+UpsertReturningContext - UpsertReturning with context
+*/
+func (pgm *Mapper) UpsertReturningContext(ctx context.Context, fields []string, values []interface{}, options UpsertOptions, returning []string, dest ...interface{}) (bool, error) {
+	SQL := pgm.generateInsertQuery(fields) + pgm.generateOnConflictQuery(fields, options)
+	SQL += returningClause(append(append([]string(nil), returning...), "(xmax = 0) AS inserted"))
+	var inserted bool
+	args := append(append([]interface{}(nil), values...), options.WhereArgs...)
+	err := pgm.queryRow(ctx, SQL, args, append(append([]interface{}(nil), dest...), &inserted))
+	return inserted, err
+}
+
+/*
+InsertBatchReturning - same as InsertBatch but returns rows with returning columns of inserted rows
+*/
+func (pgm *Mapper) InsertBatchReturning(fields []string, rows []interface{}, onDuplicate interface{}, returning []string) (*sql.Rows, error) {
+	return pgm.InsertBatchReturningContext(context.Background(), fields, rows, onDuplicate, returning)
+}
+
+/*
+InsertBatchReturningContext - InsertBatchReturning with context
+*/
+func (pgm *Mapper) InsertBatchReturningContext(ctx context.Context, fields []string, rows []interface{}, onDuplicate interface{}, returning []string) (*sql.Rows, error) {
+	if err := validateRows(fields, rows); err != nil {
+		return nil, err
+	}
+	SQL, values := pgm.generateBatchQuery(fields, rows, onDuplicate)
+	return pgm.ExecContext(ctx, SQL+returningClause(returning), values...)
+}
+
+/*
+CreateStructReturning - same as CreateStruct but fills v (pointer to struct) with all columns of the
+new row, e.g. generated id and defaults of readonly fields
+*/
+func (pgm *Mapper) CreateStructReturning(v interface{}) error {
+	return pgm.CreateStructReturningContext(context.Background(), v)
+}
+
+/*
+CreateStructReturningContext - CreateStructReturning with context
+*/
+func (pgm *Mapper) CreateStructReturningContext(ctx context.Context, v interface{}) error {
+	fields, values, _, err := structValues(v)
+	if err != nil {
+		return err
+	}
+	columns, dest, err := structTargets(v)
+	if err != nil {
+		return err
+	}
+	return pgm.CreateReturningContext(ctx, fields, values, columns, dest...)
+}
+
+/*
+SaveStructReturning - same as SaveStruct but fills v (pointer to struct) with all columns of the
+saved row and reports whether it was inserted (true) or updated (false)
+*/
+func (pgm *Mapper) SaveStructReturning(v interface{}) (bool, error) {
+	return pgm.SaveStructReturningContext(context.Background(), v)
+}
+
+/*
+SaveStructReturningContext - SaveStructReturning with context
+*/
+func (pgm *Mapper) SaveStructReturningContext(ctx context.Context, v interface{}) (bool, error) {
+	fields, values, options, err := structValues(v)
+	if err != nil {
+		return false, err
+	}
+	columns, dest, err := structTargets(v)
+	if err != nil {
+		return false, err
+	}
+	return pgm.UpsertReturningContext(ctx, fields, values, options, columns, dest...)
+}
+
+/*
+structTargets - returns columns of struct v (pointer to struct) and pointers to its fields
+*/
+func structTargets(v interface{}) ([]string, []interface{}, error) {
+	t, err := destinationType(v, reflect.Struct)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, err := getModel(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	dest := make([]interface{}, len(model.fields))
+	for i, field := range model.fields {
+		dest[i] = fieldByIndex(rv, field.index).Addr().Interface()
+	}
+	return model.columns(), dest, nil
+}
+
+func returningClause(returning []string) string {
+	if len(returning) == 0 {
+		return ""
+	}
+	return " RETURNING " + strings.Join(returning, ",")
+}
+
+func (pgm *Mapper) queryRow(ctx context.Context, SQL string, args []interface{}, dest []interface{}) error {
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	ctx, cancel := pgm.withTimeout(ctx)
+	defer cancel()
+	ctx, event := pgm.beginQuery(ctx, pgm.Source, SQL, args)
+	err := pgm.db().QueryRowContext(ctx, SQL, args...).Scan(dest...)
+	return pgm.endQuery(ctx, event, -1, err)
+}
@@ -0,0 +1,186 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+/*
+ErrInvalidDestination - returned when scan destination is not a pointer to struct or slice of structs
+*/
+var ErrInvalidDestination = errors.New("pg: destination must be a pointer to struct or to slice of structs")
+
+/*
+UnmappedColumnError - returned in StrictScan mode when result column has no struct field
+*/
+type UnmappedColumnError struct {
+	Column string
+	Type   reflect.Type
+}
+
+func (e *UnmappedColumnError) Error() string {
+	return "pg: column " + e.Column + " is not mapped to a field of " + e.Type.String()
+}
+
+/*
+LoadInto - selects columns of struct fields from source (mapper Source if empty) into dest (pointer to slice)
+*/
+func (pgm *Mapper) LoadInto(dest interface{}, source string, query interface{}, args ...interface{}) error {
+	return pgm.LoadIntoContext(context.Background(), dest, source, query, args...)
+}
+
+/*
+LoadIntoContext - LoadInto with context
+*/
+func (pgm *Mapper) LoadIntoContext(ctx context.Context, dest interface{}, source string, query interface{}, args ...interface{}) error {
+	t, err := destinationType(dest, reflect.Slice)
+	if err != nil {
+		return err
+	}
+	return pgm.loadModel(ctx, t, dest, source, query, args, "")
+}
+
+/*
+LoadOne - selects single row into dest (pointer to struct). Returns ErrNotFound if nothing is found
+*/
+func (pgm *Mapper) LoadOne(dest interface{}, source string, query interface{}, args ...interface{}) error {
+	return pgm.LoadOneContext(context.Background(), dest, source, query, args...)
+}
+
+/*
+LoadOneContext - LoadOne with context
+*/
+func (pgm *Mapper) LoadOneContext(ctx context.Context, dest interface{}, source string, query interface{}, args ...interface{}) error {
+	t, err := destinationType(dest, reflect.Struct)
+	if err != nil {
+		return err
+	}
+	return pgm.loadModel(ctx, t, dest, source, query, args, " LIMIT 1")
+}
+
+/*
+loadModel - selects columns of model t and scans rows into dest. Rows are read here, so QueryTimeout
+covers the query and scanning and is released when rows are closed
+*/
+func (pgm *Mapper) loadModel(ctx context.Context, t reflect.Type, dest interface{}, source string, query interface{}, args []interface{}, suffix string) error {
+	model, err := getModel(t)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		source = pgm.Source
+	}
+	condition, args, err := buildCondition(query, args)
+	if err != nil {
+		return err
+	}
+	SQL := "SELECT " + strings.Join(model.columns(), ", ") + " FROM " + source
+	if condition != "" {
+		SQL += " WHERE " + condition
+	}
+	ctx, cancel := pgm.withTimeout(ctx)
+	defer cancel()
+	rows, err := pgm.query(ctx, source, SQL+suffix+";", args)
+	if err != nil {
+		return err
+	}
+	return pgm.ScanRows(rows, dest)
+}
+
+/*
+ScanRows - scans rows into dest: pointer to slice of structs (or pointers to structs) or pointer to struct.
+Scanning into struct returns ErrNotFound if there are no rows. Rows are closed
+*/
+func (pgm *Mapper) ScanRows(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ErrInvalidDestination
+	}
+	v = v.Elem()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if v.Kind() == reflect.Struct {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return wrapError(sql.ErrNoRows)
+		}
+		if err := pgm.scanStruct(rows, columns, v); err != nil {
+			return err
+		}
+		return rows.Err()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return ErrInvalidDestination
+	}
+	elemType := v.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return ErrInvalidDestination
+	}
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := pgm.scanStruct(rows, columns, elem.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			v.Set(reflect.Append(v, elem))
+		} else {
+			v.Set(reflect.Append(v, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+func (pgm *Mapper) scanStruct(rows *sql.Rows, columns []string, v reflect.Value) error {
+	model, err := getModel(v.Type())
+	if err != nil {
+		return err
+	}
+	targets := make([]interface{}, len(columns))
+	for i, column := range columns {
+		field, ok := model.byColumn[column]
+		if !ok {
+			if pgm.StrictScan {
+				return &UnmappedColumnError{Column: column, Type: v.Type()}
+			}
+			targets[i] = new(interface{})
+			continue
+		}
+		targets[i] = fieldByIndex(v, field.index).Addr().Interface()
+	}
+	return rows.Scan(targets...)
+}
+
+/*
+destinationType - returns struct type of dest which must be a pointer to kind (struct or slice of structs)
+*/
+func destinationType(dest interface{}, kind reflect.Kind) (reflect.Type, error) {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != kind {
+		return nil, ErrInvalidDestination
+	}
+	t = t.Elem()
+	if kind == reflect.Slice {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, ErrInvalidDestination
+	}
+	return t, nil
+}
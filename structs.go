@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"reflect"
+)
+
+/*
+CreateStruct - inserts struct into mapper Source. Columns are read from `db` tags: readonly fields
+are skipped, omitempty fields are skipped when zero
+*/
+func (pgm *Mapper) CreateStruct(v interface{}) error {
+	return pgm.CreateStructContext(context.Background(), v)
+}
+
+/*
+CreateStructContext - CreateStruct with context
+*/
+func (pgm *Mapper) CreateStructContext(ctx context.Context, v interface{}) error {
+	fields, values, _, err := structValues(v)
+	if err != nil {
+		return err
+	}
+	return pgm.CreateContext(ctx, fields, values)
+}
+
+/*
+SaveStruct - inserts struct into mapper Source, on conflict updates it. Conflict target is the fields
+tagged with conflict option or, without them, the pk fields. Like Update, pk fields are never updated
+*/
+func (pgm *Mapper) SaveStruct(v interface{}) error {
+	return pgm.SaveStructContext(context.Background(), v)
+}
+
+/*
+SaveStructContext - SaveStruct with context
+*/
+func (pgm *Mapper) SaveStructContext(ctx context.Context, v interface{}) error {
+	fields, values, options, err := structValues(v)
+	if err != nil {
+		return err
+	}
+	return pgm.UpsertContext(ctx, fields, values, options)
+}
+
+/*
+structValues - returns insertable columns of struct v with their values and upsert options: conflict key
+columns and updated columns, which are the inserted ones except pk and conflict key columns
+*/
+func structValues(v interface{}) ([]string, []interface{}, UpsertOptions, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, UpsertOptions{}, ErrInvalidDestination
+	}
+	model, err := getModel(rv.Type())
+	if err != nil {
+		return nil, nil, UpsertOptions{}, err
+	}
+	var fields []string
+	var values []interface{}
+	options := UpsertOptions{UpdateColumns: []string{}}
+	for _, field := range model.conflictFields() {
+		options.ConflictColumns = append(options.ConflictColumns, field.quoted())
+	}
+	for _, field := range model.fields {
+		if field.readonly {
+			continue
+		}
+		value, ok := readField(rv, field.index)
+		if !ok || field.omitempty && value.IsZero() {
+			continue
+		}
+		fields = append(fields, field.quoted())
+		values = append(values, value.Interface())
+		if !field.pk && !field.conflict {
+			options.UpdateColumns = append(options.UpdateColumns, field.quoted())
+		}
+	}
+	return fields, values, options, nil
+}
+
+/*
+readField - returns field of struct v. Returns false if field belongs to nil embedded struct
+*/
+func readField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testAccount struct {
+	ID        int64  `db:"id,pk"`
+	UserID    int64  `db:"userId"`
+	Email     string `db:"email,conflict"`
+	Nickname  string `db:",omitempty"`
+	CreatedAt string `db:"created_at,readonly"`
+}
+
+func TestStructValues(t *testing.T) {
+	fields, values, options, err := structValues(&testAccount{ID: 1, UserID: 2, Email: "a@b.c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFields := []string{`"id"`, `"userid"`, `"email"`}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("fields = %v, want %v", fields, wantFields)
+	}
+	if !reflect.DeepEqual(values, []interface{}{int64(1), int64(2), "a@b.c"}) {
+		t.Errorf("values = %v", values)
+	}
+	if !reflect.DeepEqual(options.ConflictColumns, []string{`"email"`}) {
+		t.Errorf("ConflictColumns = %v", options.ConflictColumns)
+	}
+	if !reflect.DeepEqual(options.UpdateColumns, []string{`"userid"`}) {
+		t.Errorf("UpdateColumns = %v, pk and conflict columns must not be updated", options.UpdateColumns)
+	}
+}
+
+func TestStructColumnsMatchOnReadAndWrite(t *testing.T) {
+	model, err := getModel(reflect.TypeOf(testAccount{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := model.columns()
+	fields, _, _, err := structValues(&testAccount{Nickname: "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range fields {
+		if !contains(read, field) {
+			t.Errorf("written column %s is not read back from %v", field, read)
+		}
+	}
+}
+
+func TestSaveStructQuery(t *testing.T) {
+	pgm := &Mapper{Source: "accounts"}
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			"conflict column",
+			&testAccount{ID: 1, UserID: 2, Email: "a@b.c"},
+			`INSERT INTO accounts ("id","userid","email") VALUES ($1,$2,$3) ON CONFLICT ("email") DO UPDATE SET "userid" = EXCLUDED."userid"`,
+		},
+		{
+			"pk",
+			&struct {
+				ID   int64  `db:"id,pk"`
+				Name string `db:"name"`
+			}{ID: 1, Name: "n"},
+			`INSERT INTO accounts ("id","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			"nothing to update",
+			&struct {
+				ID int64 `db:"id,pk"`
+			}{ID: 1},
+			`INSERT INTO accounts ("id") VALUES ($1) ON CONFLICT ("id") DO NOTHING `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, _, options, err := structValues(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			SQL := pgm.generateInsertQuery(fields) + pgm.generateOnConflictQuery(fields, options)
+			if SQL != tt.want {
+				t.Errorf("SQL = %q\nwant  %q", SQL, tt.want)
+			}
+		})
+	}
+}
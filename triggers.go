@@ -0,0 +1,165 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+/*
+Change operations of ChangeEvent
+*/
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+)
+
+/*
+TriggerOptions - options of notification trigger installed by InstallNotifyTrigger
+*/
+type TriggerOptions struct {
+	Channel string
+	// KeyColumns - if set and row does not fit into NOTIFY payload only these columns are sent
+	// and ChangeEvent.Truncated is true. Without them such change fails with payload error
+	KeyColumns []string
+}
+
+/*
+ChangeEvent - payload sent by trigger installed with InstallNotifyTrigger
+*/
+type ChangeEvent struct {
+	Op        string          `json:"op"`
+	Schema    string          `json:"schema"`
+	Table     string          `json:"table"`
+	Old       json.RawMessage `json:"old"`
+	New       json.RawMessage `json:"new"`
+	Truncated bool            `json:"truncated"`
+}
+
+/*
+DecodeOld - decodes row before change (UPDATE, DELETE) into v
+*/
+func (e *ChangeEvent) DecodeOld(v interface{}) error {
+	return decodeRow(e.Old, v)
+}
+
+/*
+DecodeNew - decodes row after change (INSERT, UPDATE) into v
+*/
+func (e *ChangeEvent) DecodeNew(v interface{}) error {
+	return decodeRow(e.New, v)
+}
+
+func decodeRow(row json.RawMessage, v interface{}) error {
+	if len(row) == 0 || string(row) == "null" {
+		return nil
+	}
+	return json.Unmarshal(row, v)
+}
+
+/*
+HandleChanges - registers handler for ChangeEvent notifications of the channel
+*/
+func (pgm *Mapper) HandleChanges(channel string, handler func(channel string, event *ChangeEvent) error) {
+	pgm.Handle(channel, ChangeEvent{}, func(channel string, payload interface{}) error {
+		return handler(channel, payload.(*ChangeEvent))
+	})
+}
+
+/*
+InstallNotifyTrigger - creates (or replaces) trigger sending ChangeEvent to channel on INSERT/UPDATE/DELETE of table
+*/
+func (pgm *Mapper) InstallNotifyTrigger(table string, options TriggerOptions) error {
+	return pgm.InstallNotifyTriggerContext(context.Background(), table, options)
+}
+
+/*
+InstallNotifyTriggerContext - InstallNotifyTrigger with context
+*/
+func (pgm *Mapper) InstallNotifyTriggerContext(ctx context.Context, table string, options TriggerOptions) error {
+	return pgm.execScript(ctx, table, generateNotifyTrigger(table, options))
+}
+
+/*
+DropNotifyTrigger - drops trigger and function created by InstallNotifyTrigger
+*/
+func (pgm *Mapper) DropNotifyTrigger(table string) error {
+	return pgm.DropNotifyTriggerContext(context.Background(), table)
+}
+
+/*
+DropNotifyTriggerContext - DropNotifyTrigger with context
+*/
+func (pgm *Mapper) DropNotifyTriggerContext(ctx context.Context, table string) error {
+	function, trigger := notifyTriggerNames(table)
+	SQL := "DROP TRIGGER IF EXISTS " + trigger + " ON " + quoteIdentifier(table) + ";\n" +
+		"DROP FUNCTION IF EXISTS " + function + "();"
+	return pgm.execScript(ctx, table, SQL)
+}
+
+/*
+execScript - executes several statements on table without arguments
+*/
+func (pgm *Mapper) execScript(ctx context.Context, table, SQL string) error {
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	ctx, cancel := pgm.withTimeout(ctx)
+	defer cancel()
+	_, err := pgm.execDirect(ctx, table, SQL)
+	return err
+}
+
+/*
+notifyTriggerNames - returns quoted names of trigger function (in the schema of the table) and trigger
+*/
+func notifyTriggerNames(table string) (string, string) {
+	parts := splitIdentifier(table)
+	trigger := pq.QuoteIdentifier(resolveName(parts[len(parts)-1]) + "_notify")
+	if len(parts) == 1 {
+		return trigger, trigger
+	}
+	return quoteIdentifier(strings.Join(parts[:len(parts)-1], ".")) + "." + trigger, trigger
+}
+
+func generateNotifyTrigger(table string, options TriggerOptions) string {
+	function, trigger := notifyTriggerNames(table)
+	SQL := "CREATE OR REPLACE FUNCTION " + function + "() RETURNS trigger AS $pgnotify$\n" +
+		"DECLARE\n" +
+		"\told_row json;\n" +
+		"\tnew_row json;\n" +
+		"\tpayload text;\n" +
+		"BEGIN\n" +
+		"\tIF TG_OP IN ('UPDATE', 'DELETE') THEN old_row := row_to_json(OLD); END IF;\n" +
+		"\tIF TG_OP IN ('INSERT', 'UPDATE') THEN new_row := row_to_json(NEW); END IF;\n" +
+		"\tpayload := json_build_object('op', TG_OP, 'schema', TG_TABLE_SCHEMA, 'table', TG_TABLE_NAME, " +
+		"'old', old_row, 'new', new_row)::text;\n"
+	if len(options.KeyColumns) > 0 {
+		SQL += "\tIF octet_length(payload) >= " + strconv.Itoa(MaxNotifyPayload) + " THEN\n" +
+			"\t\tIF old_row IS NOT NULL THEN old_row := " + keysObject("OLD", options.KeyColumns) + "; END IF;\n" +
+			"\t\tIF new_row IS NOT NULL THEN new_row := " + keysObject("NEW", options.KeyColumns) + "; END IF;\n" +
+			"\t\tpayload := json_build_object('op', TG_OP, 'schema', TG_TABLE_SCHEMA, 'table', TG_TABLE_NAME, " +
+			"'old', old_row, 'new', new_row, 'truncated', true)::text;\n" +
+			"\tEND IF;\n"
+	}
+	SQL += "\tPERFORM pg_notify(" + pq.QuoteLiteral(options.Channel) + ", payload);\n" +
+		"\tRETURN NULL;\n" +
+		"END;\n" +
+		"$pgnotify$ LANGUAGE plpgsql;\n"
+	SQL += "DROP TRIGGER IF EXISTS " + trigger + " ON " + quoteIdentifier(table) + ";\n" +
+		"CREATE TRIGGER " + trigger + " AFTER INSERT OR UPDATE OR DELETE ON " + quoteIdentifier(table) +
+		" FOR EACH ROW EXECUTE PROCEDURE " + function + "();"
+	return SQL
+}
+
+func keysObject(record string, columns []string) string {
+	var pairs []string
+	for _, column := range columns {
+		pairs = append(pairs, pq.QuoteLiteral(resolveName(column))+", "+record+"."+quoteIdentifier(column))
+	}
+	return "json_build_object(" + strings.Join(pairs, ", ") + ")"
+}
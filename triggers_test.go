@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotifyTriggerNames(t *testing.T) {
+	tests := []struct {
+		table    string
+		function string
+		trigger  string
+	}{
+		{"orders", `"orders_notify"`, `"orders_notify"`},
+		{"shop.orders", `"shop"."orders_notify"`, `"orders_notify"`},
+		{`"Shop"."Orders"`, `"Shop"."Orders_notify"`, `"Orders_notify"`},
+	}
+	for _, tt := range tests {
+		function, trigger := notifyTriggerNames(tt.table)
+		if function != tt.function || trigger != tt.trigger {
+			t.Errorf("notifyTriggerNames(%q) = %s, %s, want %s, %s", tt.table, function, trigger, tt.function, tt.trigger)
+		}
+	}
+}
+
+func TestGenerateNotifyTrigger(t *testing.T) {
+	SQL := generateNotifyTrigger("shop.orders", TriggerOptions{Channel: "order's"})
+	for _, want := range []string{
+		`CREATE OR REPLACE FUNCTION "shop"."orders_notify"() RETURNS trigger AS $pgnotify$`,
+		`PERFORM pg_notify('order''s', payload);`,
+		`DROP TRIGGER IF EXISTS "orders_notify" ON "shop"."orders";`,
+		`CREATE TRIGGER "orders_notify" AFTER INSERT OR UPDATE OR DELETE ON "shop"."orders" FOR EACH ROW EXECUTE PROCEDURE "shop"."orders_notify"();`,
+	} {
+		if !strings.Contains(SQL, want) {
+			t.Errorf("trigger SQL does not contain %s:\n%s", want, SQL)
+		}
+	}
+	if strings.Contains(SQL, "truncated") {
+		t.Error("trigger without KeyColumns truncates payload")
+	}
+
+	SQL = generateNotifyTrigger("orders", TriggerOptions{Channel: "orders", KeyColumns: []string{"id", `"Tenant"`}})
+	for _, want := range []string{
+		"IF octet_length(payload) >= 8000 THEN",
+		`new_row := json_build_object('id', NEW."id", 'Tenant', NEW."Tenant")`,
+		"'truncated', true",
+	} {
+		if !strings.Contains(SQL, want) {
+			t.Errorf("trigger SQL does not contain %s:\n%s", want, SQL)
+		}
+	}
+}
+
+func TestHandleChanges(t *testing.T) {
+	pgm := &Mapper{}
+	var got *ChangeEvent
+	pgm.HandleChanges("orders", func(channel string, event *ChangeEvent) error {
+		got = event
+		return nil
+	})
+	payload := `{"op":"UPDATE","schema":"public","table":"orders","old":{"id":1,"total":5},"new":{"id":1,"total":7}}`
+	if err := pgm.handleNotification("orders", []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Op != OpUpdate || got.Table != "orders" {
+		t.Fatalf("event = %+v", got)
+	}
+	var before, after struct{ Total int }
+	if err := got.DecodeOld(&before); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.DecodeNew(&after); err != nil {
+		t.Fatal(err)
+	}
+	if before.Total != 5 || after.Total != 7 {
+		t.Errorf("old total %d, new total %d", before.Total, after.Total)
+	}
+}
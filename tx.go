@@ -0,0 +1,111 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+/*
+executor - operations shared by *sql.DB and *sql.Tx
+*/
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+/*
+db - returns transaction of Tx-bound mapper or connection pool
+*/
+func (pgm *Mapper) db() executor {
+	if pgm.tx != nil {
+		return pgm.tx
+	}
+	return pgm.Conn
+}
+
+/*
+Tx - runs fn in transaction. All operations of tx mapper passed to fn run in the transaction.
+Transaction is committed if fn returns nil and rolled back if it returns error or panics.
+Tx called on tx mapper creates a savepoint instead of a new transaction
+*/
+func (pgm *Mapper) Tx(ctx context.Context, fn func(tx *Mapper) error) error {
+	return pgm.TxOptions(ctx, nil, fn)
+}
+
+/*
+TxOptions - same as Tx with isolation level and read-only options. Options are ignored for savepoints
+*/
+func (pgm *Mapper) TxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *Mapper) error) (err error) {
+	if pgm.tx != nil {
+		return pgm.savepoint(ctx, fn)
+	}
+	if err := pgm.checkConnection(); err != nil {
+		return err
+	}
+	tx, err := pgm.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return wrapError(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(pgm.withTx(tx, 0)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return wrapError(tx.Commit())
+}
+
+func (pgm *Mapper) savepoint(ctx context.Context, fn func(tx *Mapper) error) error {
+	nested := pgm.withTx(pgm.tx, pgm.txLevel+1)
+	name := "sp_" + strconv.Itoa(nested.txLevel)
+	if _, err := pgm.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			pgm.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+	if err := fn(nested); err != nil {
+		pgm.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+	_, err := pgm.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+/*
+withTx - returns copy of mapper bound to transaction
+*/
+func (pgm *Mapper) withTx(tx *sql.Tx, level int) *Mapper {
+	return &Mapper{
+		DBConfig:       pgm.DBConfig,
+		Conn:           pgm.Conn,
+		Source:         pgm.Source,
+		ConnectionInfo: pgm.ConnectionInfo,
+		ErrorHandler:   pgm.ErrorHandler,
+		QueryTimeout:   pgm.QueryTimeout,
+		StrictScan:     pgm.StrictScan,
+		CopyThreshold:  pgm.CopyThreshold,
+		BatchProgress:  pgm.BatchProgress,
+		Logger:         pgm.Logger,
+		hooks:          pgm.hooks,
+		tx:             tx,
+		txLevel:        level,
+	}
+}
+
+/*
+InTx - reports whether mapper is bound to transaction
+*/
+func (pgm *Mapper) InTx() bool {
+	return pgm.tx != nil
+}
@@ -0,0 +1,104 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name       string
+		fn         func(tx *Mapper) error
+		statements []string
+		err        error
+		panics     bool
+	}{
+		{
+			name:       "commit",
+			fn:         func(tx *Mapper) error { return nil },
+			statements: []string{"BEGIN", "COMMIT"},
+		},
+		{
+			name:       "rollback on error",
+			fn:         func(tx *Mapper) error { return failed },
+			statements: []string{"BEGIN", "ROLLBACK"},
+			err:        failed,
+		},
+		{
+			name:       "rollback on panic",
+			fn:         func(tx *Mapper) error { panic(failed) },
+			statements: []string{"BEGIN", "ROLLBACK"},
+			panics:     true,
+		},
+		{
+			name: "nested savepoints",
+			fn: func(tx *Mapper) error {
+				return tx.Tx(context.Background(), func(sp *Mapper) error {
+					return sp.Tx(context.Background(), func(*Mapper) error { return nil })
+				})
+			},
+			statements: []string{"BEGIN", "SAVEPOINT sp_1", "SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			name: "savepoint rolled back, transaction committed",
+			fn: func(tx *Mapper) error {
+				if err := tx.Tx(context.Background(), func(*Mapper) error { return failed }); err != failed {
+					return errors.New("savepoint error is not returned")
+				}
+				return tx.Tx(context.Background(), func(*Mapper) error { return nil })
+			},
+			statements: []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			name: "panic in savepoint",
+			fn: func(tx *Mapper) error {
+				return tx.Tx(context.Background(), func(*Mapper) error { panic(failed) })
+			},
+			statements: []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"},
+			panics:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgm, driver := fakeMapper(t, "users")
+			var err error
+			panicked := func() (panicked bool) {
+				defer func() {
+					panicked = recover() != nil
+				}()
+				err = pgm.Tx(context.Background(), tt.fn)
+				return false
+			}()
+			if panicked != tt.panics {
+				t.Errorf("panicked = %v, want %v", panicked, tt.panics)
+			}
+			if err != tt.err {
+				t.Errorf("Tx() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(driver.statements, tt.statements) {
+				t.Errorf("statements = %q, want %q", driver.statements, tt.statements)
+			}
+		})
+	}
+}
+
+func TestTxMapper(t *testing.T) {
+	pgm, _ := fakeMapper(t, "users")
+	pgm.Tx(context.Background(), func(tx *Mapper) error {
+		if !tx.InTx() || tx.txLevel != 0 || tx.Source != "users" {
+			t.Errorf("tx mapper = in tx %v, level %d, source %q", tx.InTx(), tx.txLevel, tx.Source)
+		}
+		return tx.Tx(context.Background(), func(sp *Mapper) error {
+			if sp.txLevel != 1 || sp.tx != tx.tx {
+				t.Errorf("savepoint mapper level = %d, want 1 in the same transaction", sp.txLevel)
+			}
+			return nil
+		})
+	})
+	if pgm.InTx() {
+		t.Error("mapper is bound to transaction after Tx")
+	}
+}
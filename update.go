@@ -0,0 +1,204 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+ErrMissingWhere - returned by Update and Delete called without condition
+*/
+var ErrMissingWhere = errors.New("pg: update and delete require a condition, use AllRows to affect every row")
+
+/*
+Update - updates rows of mapper Source matching where and returns number of affected rows.
+set is map[string]interface{} (keys are column names written as in SQL like Where keys) or a struct (readonly
+and pk fields are not updated). For a struct with nil where the row is matched by pk fields
+*/
+func (pgm *Mapper) Update(set interface{}, where interface{}, args ...interface{}) (int64, error) {
+	return pgm.UpdateContext(context.Background(), set, where, args...)
+}
+
+/*
+UpdateContext - Update with context
+*/
+func (pgm *Mapper) UpdateContext(ctx context.Context, set interface{}, where interface{}, args ...interface{}) (int64, error) {
+	SQL, values, err := pgm.generateUpdateQuery(set, where, args)
+	if err != nil {
+		return 0, err
+	}
+	result, err := pgm.executeResult(ctx, SQL, values)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/*
+UpdateReturning - same as Update but returns rows with returning columns of updated rows
+*/
+func (pgm *Mapper) UpdateReturning(set interface{}, returning []string, where interface{}, args ...interface{}) (*sql.Rows, error) {
+	return pgm.UpdateReturningContext(context.Background(), set, returning, where, args...)
+}
+
+/*
+UpdateReturningContext - UpdateReturning with context
+*/
+func (pgm *Mapper) UpdateReturningContext(ctx context.Context, set interface{}, returning []string, where interface{}, args ...interface{}) (*sql.Rows, error) {
+	SQL, values, err := pgm.generateUpdateQuery(set, where, args)
+	if err != nil {
+		return nil, err
+	}
+	return pgm.ExecContext(ctx, SQL+returningClause(returning), values...)
+}
+
+/*
+Delete - deletes rows of mapper Source matching where and returns number of affected rows
+*/
+func (pgm *Mapper) Delete(where interface{}, args ...interface{}) (int64, error) {
+	return pgm.DeleteContext(context.Background(), where, args...)
+}
+
+/*
+DeleteContext - Delete with context
+*/
+func (pgm *Mapper) DeleteContext(ctx context.Context, where interface{}, args ...interface{}) (int64, error) {
+	SQL, values, err := pgm.generateDeleteQuery(where, args)
+	if err != nil {
+		return 0, err
+	}
+	result, err := pgm.executeResult(ctx, SQL, values)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+/*
+DeleteReturning - same as Delete but returns rows with returning columns of deleted rows
+*/
+func (pgm *Mapper) DeleteReturning(returning []string, where interface{}, args ...interface{}) (*sql.Rows, error) {
+	return pgm.DeleteReturningContext(context.Background(), returning, where, args...)
+}
+
+/*
+DeleteReturningContext - DeleteReturning with context
+*/
+func (pgm *Mapper) DeleteReturningContext(ctx context.Context, returning []string, where interface{}, args ...interface{}) (*sql.Rows, error) {
+	SQL, values, err := pgm.generateDeleteQuery(where, args)
+	if err != nil {
+		return nil, err
+	}
+	return pgm.ExecContext(ctx, SQL+returningClause(returning), values...)
+}
+
+func (pgm *Mapper) generateUpdateQuery(set interface{}, where interface{}, args []interface{}) (string, []interface{}, error) {
+	fields, values, keys, err := updateValues(set)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(fields) == 0 {
+		return "", nil, errors.New("pg: nothing to update")
+	}
+	if where == nil && keys != nil {
+		where, args = keys, nil
+	}
+	var assignments []string
+	for i, field := range fields {
+		assignments = append(assignments, field+" = $"+strconv.Itoa(i+1))
+	}
+	SQL := "UPDATE " + pgm.Source + " SET " + strings.Join(assignments, ",")
+	condition, args, err := whereClause(where, args, len(values))
+	if err != nil {
+		return "", nil, err
+	}
+	return SQL + condition, append(values, args...), nil
+}
+
+func (pgm *Mapper) generateDeleteQuery(where interface{}, args []interface{}) (string, []interface{}, error) {
+	condition, args, err := whereClause(where, args, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return "DELETE FROM " + pgm.Source + condition, args, nil
+}
+
+/*
+whereClause - returns WHERE clause with placeholders numbered after offset bound arguments.
+Missing condition is an error, AllRows gives an empty clause
+*/
+func whereClause(where interface{}, args []interface{}, offset int) (string, []interface{}, error) {
+	if _, ok := where.(allRows); ok {
+		return "", args, nil
+	}
+	condition, args, err := buildCondition(where, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if condition == "" {
+		return "", nil, ErrMissingWhere
+	}
+	return " WHERE " + rebind(condition, offset), args, nil
+}
+
+/*
+updateValues - returns quoted updated columns and values of set (map or struct).
+For struct pk columns and values are returned as Where
+*/
+func updateValues(set interface{}) ([]string, []interface{}, Where, error) {
+	var m map[string]interface{}
+	switch s := set.(type) {
+	case map[string]interface{}:
+		m = s
+	case Where:
+		m = s
+	}
+	if m != nil {
+		var fields []string
+		for field := range m {
+			fields = append(fields, field)
+		}
+		sortedStrings(fields)
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i] = m[field]
+			fields[i] = quoteIdentifier(field)
+		}
+		return fields, values, nil, nil
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(set))
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, nil, ErrInvalidDestination
+	}
+	model, err := getModel(rv.Type())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var fields []string
+	var values []interface{}
+	var keys Where
+	for _, field := range model.fields {
+		value, ok := readField(rv, field.index)
+		if !ok {
+			continue
+		}
+		if field.pk {
+			if keys == nil {
+				keys = Where{}
+			}
+			keys[field.column] = value.Interface()
+			continue
+		}
+		if field.readonly || field.omitempty && value.IsZero() {
+			continue
+		}
+		fields = append(fields, field.quoted())
+		values = append(values, value.Interface())
+	}
+	return fields, values, keys, nil
+}
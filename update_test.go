@@ -0,0 +1,76 @@
+package pg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateUpdateQuery(t *testing.T) {
+	pgm := &Mapper{Source: "accounts"}
+	tests := []struct {
+		name  string
+		set   interface{}
+		where interface{}
+		args  []interface{}
+		SQL   string
+		want  []interface{}
+		err   error
+	}{
+		{
+			name:  "map with string condition",
+			set:   map[string]interface{}{"status": "active", "userId": 2, `"Tenant"`: 3},
+			where: "id = $1",
+			args:  []interface{}{1},
+			SQL:   `UPDATE accounts SET "Tenant" = $1,"status" = $2,"userid" = $3 WHERE id = $4`,
+			want:  []interface{}{3, "active", 2, 1},
+		},
+		{
+			name:  "where",
+			set:   Where{"status": "active"},
+			where: Where{"id": 1},
+			SQL:   `UPDATE accounts SET "status" = $1 WHERE "id" = $2`,
+			want:  []interface{}{"active", 1},
+		},
+		{
+			name: "struct matched by pk",
+			set:  &testAccount{ID: 1, UserID: 2, Email: "a@b.c"},
+			SQL:  `UPDATE accounts SET "userid" = $1,"email" = $2 WHERE "id" = $3`,
+			want: []interface{}{int64(2), "a@b.c", int64(1)},
+		},
+		{
+			name:  "all rows",
+			set:   Where{"status": "archived"},
+			where: AllRows,
+			SQL:   `UPDATE accounts SET "status" = $1`,
+			want:  []interface{}{"archived"},
+		},
+		{
+			name: "missing where",
+			set:  Where{"status": "archived"},
+			err:  ErrMissingWhere,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SQL, args, err := pgm.generateUpdateQuery(tt.set, tt.where, tt.args)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if SQL != tt.SQL || !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("got %q %v\nwant %q %v", SQL, args, tt.SQL, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDeleteQuery(t *testing.T) {
+	pgm := &Mapper{Source: "accounts"}
+	SQL, args, err := pgm.generateDeleteQuery("id = ANY($1)", []interface{}{[]int{1}})
+	if err != nil || SQL != "DELETE FROM accounts WHERE id = ANY($1)" || len(args) != 1 {
+		t.Errorf("got %q %v %v", SQL, args, err)
+	}
+	if _, _, err := pgm.generateDeleteQuery(nil, nil); !errors.Is(err, ErrMissingWhere) {
+		t.Errorf("err = %v, want ErrMissingWhere", err)
+	}
+}
@@ -0,0 +1,36 @@
+package pg
+
+import "context"
+
+/*
+UpsertOptions - INSERT ... ON CONFLICT options. Without ConflictColumns and Constraint conflicting rows
+are skipped (DO NOTHING)
+*/
+type UpsertOptions struct {
+	// ConflictColumns - conflict target columns in the given order
+	ConflictColumns []string
+	// Constraint - name of the constraint used as conflict target instead of ConflictColumns
+	Constraint string
+	// UpdateColumns - columns set to EXCLUDED values. If nil all fields except ConflictColumns are updated,
+	// if empty conflicting rows are skipped
+	UpdateColumns []string
+	// Where - condition of DO UPDATE, its $n placeholders are bound to WhereArgs
+	Where     string
+	WhereArgs []interface{}
+}
+
+/*
+Upsert - inserts row into mapper Source, on conflict updates UpdateColumns with EXCLUDED values
+*/
+func (pgm *Mapper) Upsert(fields []string, values []interface{}, options UpsertOptions) error {
+	return pgm.UpsertContext(context.Background(), fields, values, options)
+}
+
+/*
+UpsertContext - Upsert with context
+*/
+func (pgm *Mapper) UpsertContext(ctx context.Context, fields []string, values []interface{}, options UpsertOptions) error {
+	SQL := pgm.generateInsertQuery(fields)
+	SQL += pgm.generateOnConflictQuery(fields, options)
+	return pgm.execute(ctx, SQL, append(append([]interface{}(nil), values...), options.WhereArgs...))
+}
@@ -0,0 +1,59 @@
+package pg
+
+import "testing"
+
+func TestGenerateOnConflictQuery(t *testing.T) {
+	pgm := &Mapper{Source: "users"}
+	fields := []string{"id", "email", "name"}
+	tests := []struct {
+		name    string
+		options UpsertOptions
+		want    string
+	}{
+		{"no target", UpsertOptions{}, " ON CONFLICT DO NOTHING "},
+		{
+			"columns",
+			UpsertOptions{ConflictColumns: []string{"id"}},
+			" ON CONFLICT (id) DO UPDATE SET email = EXCLUDED.email,name = EXCLUDED.name",
+		},
+		{
+			"ordered columns",
+			UpsertOptions{ConflictColumns: []string{"email", "id"}},
+			" ON CONFLICT (email,id) DO UPDATE SET name = EXCLUDED.name",
+		},
+		{
+			"constraint",
+			UpsertOptions{Constraint: "users_email_key", UpdateColumns: []string{"name"}},
+			" ON CONFLICT ON CONSTRAINT users_email_key DO UPDATE SET name = EXCLUDED.name",
+		},
+		{
+			"empty update columns",
+			UpsertOptions{ConflictColumns: []string{"id"}, UpdateColumns: []string{}},
+			" ON CONFLICT (id) DO NOTHING ",
+		},
+		{
+			"all columns are conflict target",
+			UpsertOptions{ConflictColumns: fields},
+			" ON CONFLICT (id,email,name) DO NOTHING ",
+		},
+		{
+			"where",
+			UpsertOptions{ConflictColumns: []string{"id"}, UpdateColumns: []string{"name"}, Where: "users.version < $1"},
+			" ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name WHERE users.version < $4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pgm.generateOnConflictQuery(fields, tt.options); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateInsertQuery(t *testing.T) {
+	pgm := &Mapper{Source: "users"}
+	if got := pgm.generateInsertQuery([]string{"id", "name"}); got != "INSERT INTO users (id,name) VALUES ($1,$2)" {
+		t.Errorf("got %q", got)
+	}
+}
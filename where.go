@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+/*
+Where - structured condition: columns compared with values and joined with AND.
+Nil values are compared with IS NULL, slices with = ANY. Keys are column names written as in SQL:
+unquoted names are folded to lower case, "quoted" names keep their case
+*/
+type Where map[string]interface{}
+
+/*
+AllRows - condition matching every row. Update and Delete refuse to run without a condition unless it is AllRows
+*/
+var AllRows = allRows{}
+
+type allRows struct{}
+
+/*
+buildCondition - returns SQL condition and its arguments. query is either a string condition with
+$n placeholders bound to args or Where (map[string]interface{}). Args are rejected with Where
+and without condition as they have no placeholders to bind to
+*/
+func buildCondition(query interface{}, args []interface{}) (string, []interface{}, error) {
+	switch q := query.(type) {
+	case string:
+		return q, args, nil
+	case nil, allRows:
+		return "", nil, unexpectedArgs(query, args)
+	case Where:
+		if err := unexpectedArgs(query, args); err != nil {
+			return "", nil, err
+		}
+		return buildWhere(q)
+	case map[string]interface{}:
+		if err := unexpectedArgs(query, args); err != nil {
+			return "", nil, err
+		}
+		return buildWhere(q)
+	}
+	return "", nil, fmt.Errorf("pg: unsupported condition type %T", query)
+}
+
+func unexpectedArgs(query interface{}, args []interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pg: %d arguments passed with %T condition, only string condition takes arguments", len(args), query)
+}
+
+func buildWhere(where map[string]interface{}) (string, []interface{}, error) {
+	columns := make([]string, 0, len(where))
+	for column := range where {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var conditions []string
+	var args []interface{}
+	for _, column := range columns {
+		value := where[column]
+		name := quoteIdentifier(column)
+		if value == nil {
+			conditions = append(conditions, name+" IS NULL")
+			continue
+		}
+		placeholder := "$" + strconv.Itoa(len(args)+1)
+		if isSlice(value) {
+			conditions = append(conditions, name+" = ANY("+placeholder+")")
+			args = append(args, pq.Array(value))
+			continue
+		}
+		conditions = append(conditions, name+" = "+placeholder)
+		args = append(args, value)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+func isSlice(value interface{}) bool {
+	if _, ok := value.(driver.Valuer); ok {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	return v.Type().Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestBuildCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     interface{}
+		args      []interface{}
+		condition string
+		want      []interface{}
+		err       bool
+	}{
+		{"nil", nil, nil, "", nil, false},
+		{"all rows", AllRows, nil, "", nil, false},
+		{"string", "id = $1", []interface{}{1}, "id = $1", []interface{}{1}, false},
+		{"where", Where{"id": 1}, nil, `"id" = $1`, []interface{}{1}, false},
+		{"map", map[string]interface{}{"id": 1}, nil, `"id" = $1`, []interface{}{1}, false},
+		{"where with args", Where{"id": 1}, []interface{}{2}, "", nil, true},
+		{"map with args", map[string]interface{}{"id": 1}, []interface{}{2}, "", nil, true},
+		{"nil with args", nil, []interface{}{2}, "", nil, true},
+		{"unsupported", 42, nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, err := buildCondition(tt.query, tt.args)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if condition != tt.condition || !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("got %q %v, want %q %v", condition, args, tt.condition, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildWhere(t *testing.T) {
+	tests := []struct {
+		name      string
+		where     Where
+		condition string
+		args      []interface{}
+	}{
+		{"sorted columns", Where{"b": 2, "a": 1}, `"a" = $1 AND "b" = $2`, []interface{}{1, 2}},
+		{"null", Where{"deleted_at": nil, "id": 1}, `"deleted_at" IS NULL AND "id" = $1`, []interface{}{1}},
+		{"slice", Where{"id": []int{1, 2}}, `"id" = ANY($1)`, []interface{}{pq.Array([]int{1, 2})}},
+		{"bytes are not slice", Where{"data": []byte("x")}, `"data" = $1`, []interface{}{[]byte("x")}},
+		{"qualified column", Where{"u.id": 1}, `"u"."id" = $1`, []interface{}{1}},
+		{"mixed case folded", Where{"userId": 1}, `"userid" = $1`, []interface{}{1}},
+		{"quoted mixed case", Where{`"userId"`: 1}, `"userId" = $1`, []interface{}{1}},
+		{"quoted qualified", Where{`"Users"."id"`: 1}, `"Users"."id" = $1`, []interface{}{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, err := buildWhere(tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if condition != tt.condition || !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("got %q %v, want %q %v", condition, args, tt.condition, tt.args)
+			}
+		})
+	}
+}